@@ -1,11 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"time"
 
-	"github.com/gorilla/feeds"
-	"github.com/otiai10/opengraph"
 	"gopkg.in/urfave/cli.v1"
 
 	"github.com/flix-tech/confs.tech.push/confs"
@@ -14,57 +14,84 @@ import (
 func AtomCommand() cli.Command {
 	return cli.Command{
 		Name:   "atom",
-		Usage:  "generate atom feed",
-		Action: wrapAction(atomAction),
-		Flags:  []cli.Flag{},
+		Usage:  "generate atom, rss or json feed",
+		Action: wrapOutputAction(atomAction, func(c *cli.Context) string { return "." + c.String("format") }),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: "atom",
+				Usage: "Feed format: atom, rss or json (JSON Feed)",
+			},
+			cli.StringFlag{
+				Name:  "state-file, s",
+				Value: "atom.json",
+				Usage: "State file path keeping when the entries were first published",
+			},
+			outputFlag,
+		},
 	}
 }
 
-func atomAction(topic string, conferences []confs.Conference, c *cli.Context) error {
-	atom, err := generateAtomFeed(topic, conferences)
+func atomAction(topic string, conferences []confs.Conference, c *cli.Context) (string, error) {
+	stateFile := c.String("state-file")
+	entries := updateFeedEntries(confs.LoadFeedState(stateFile), conferences, time.Now())
+
+	feed, err := generateFeed(context.Background(), topic, conferences, entries, c.String("format"))
 	if err != nil {
-		return err
+		return "", err
 	}
+	feed += "\n"
 
-	fmt.Println(atom)
+	if delivery.DryRun {
+		return feed, nil
+	}
 
-	return nil
+	return feed, confs.SaveFeedState(stateFile, entries)
 }
 
-func generateAtomFeed(topic string, conferences []confs.Conference) (string, error) {
-	now := time.Now()
-	feed := &feeds.Feed{
-		Title:   topic + " tech conferences",
-		Link:    &feeds.Link{Href: fmt.Sprintf("https://confs.tech/%s", topic)},
-		Author:  &feeds.Author{Name: "https://confs.tech/"},
-		Created: now,
-	}
-
-	items := []*feeds.Item{}
+// updateFeedEntries adds the conferences seen for the first time and bumps
+// the update time of the ones whose data changed.
+func updateFeedEntries(entries []confs.FeedEntry, conferences []confs.Conference, now time.Time) []confs.FeedEntry {
 	for _, c := range conferences {
-		og, err := opengraph.Fetch(c.URL)
-		if err != nil {
-			og = opengraph.New(c.URL) // Ignoring the error, opengraph data is not critical
+		i := findFeedEntry(entries, c)
+		if i < 0 {
+			entries = append(entries, confs.FeedEntry{Conference: c, FirstSeen: now, Updated: now})
+			continue
 		}
 
-		body := fmt.Sprintf("<p>%s・%s</p>", formatLocation(c), formatDateRange(c))
-		if og.Description != "" {
-			body += fmt.Sprintf("<p>%s</p>", og.Description)
+		if entries[i].Conference != c {
+			entries[i].Conference = c
+			entries[i].Updated = now
 		}
-		if len(og.Image) > 0 {
-			body += fmt.Sprintf("<p><img src=\"%s\" alt=\"img\" /></p>", og.Image[0].URL)
+	}
+
+	return entries
+}
+
+// findFeedEntry looks the conference up by its entry id, so every edition of
+// a conference gets its own entry.
+func findFeedEntry(entries []confs.FeedEntry, c confs.Conference) int {
+	for i, e := range entries {
+		if formatTagURI(e.Conference) == formatTagURI(c) {
+			return i
 		}
+	}
 
-		items = append(items, &feeds.Item{
-			Title:       c.Name,
-			Link:        &feeds.Link{Href: c.URL},
-			Id:          c.URL,
-			Description: body,
-			Created:     now,
-		})
+	return -1
+}
+
+// formatTagURI returns a tag URI (RFC 4151) from the conference url and start
+// date, e.g. tag:go1.com,2019-08-20:/ for https://go1.com/.
+func formatTagURI(c confs.Conference) string {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Sprintf("tag:confs.tech,%s:%s", c.StartDate, url.PathEscape(c.URL))
 	}
 
-	feed.Items = items
+	specific := u.EscapedPath()
+	if u.RawQuery != "" {
+		specific += "?" + u.RawQuery
+	}
 
-	return feed.ToAtom()
+	return fmt.Sprintf("tag:%s,%s:%s", u.Hostname(), c.StartDate, specific)
 }
@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/flix-tech/confs.tech.push/confs"
 )
 
 func TestAtomGeneration(t *testing.T) {
-	_, err := generateAtomFeed("golang", []confs.Conference{
+	_, err := generateFeed(context.Background(), "golang", []confs.Conference{
 		confs.Conference{
 			Name:      "Go one",
 			URL:       "https://go1.com/",
@@ -24,9 +27,47 @@ func TestAtomGeneration(t *testing.T) {
 			City:      "Mariupol",
 			Country:   "Ukraine",
 		},
-	})
+	}, []confs.FeedEntry{}, "atom")
 
 	if err != nil {
 		t.Errorf("Got error when generating conferences atom: %s", err)
 	}
 }
+
+func TestAtomEntriesKeepTimestampsUntilContentChanges(t *testing.T) {
+	conference := confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1?edition=eu", StartDate: "2019-08-20", EndDate: "2019-08-20", City: "Berlin", Country: "Germany"}
+	firstSeen := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	changed := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	entries := updateFeedEntries([]confs.FeedEntry{}, []confs.Conference{conference}, firstSeen)
+	entries = updateFeedEntries(entries, []confs.Conference{conference}, changed)
+	if len(entries) != 1 || !entries[0].FirstSeen.Equal(firstSeen) || !entries[0].Updated.Equal(firstSeen) {
+		t.Fatalf("Expected unchanged entry to keep its timestamps, got %+v", entries)
+	}
+
+	conference.EndDate = "2019-08-21"
+	entries = updateFeedEntries(entries, []confs.Conference{conference}, changed)
+	if len(entries) != 1 || !entries[0].FirstSeen.Equal(firstSeen) || !entries[0].Updated.Equal(changed) {
+		t.Fatalf("Expected changed entry to be updated, got %+v", entries)
+	}
+
+	atom, err := generateFeed(context.Background(), "golang", []confs.Conference{conference}, entries, "atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"<id>tag:127.0.0.1,2019-08-20:/go1?edition=eu</id>",
+		"<updated>2019-06-01T10:00:00Z</updated>",
+	} {
+		if !strings.Contains(atom, expected) {
+			t.Errorf("Expected %s in feed %s", expected, atom)
+		}
+	}
+
+	nextEdition := conference
+	nextEdition.StartDate, nextEdition.EndDate = "2020-08-20", "2020-08-20"
+	entries = updateFeedEntries(entries, []confs.Conference{nextEdition}, changed)
+	if len(entries) != 2 || formatTagURI(nextEdition) == formatTagURI(conference) {
+		t.Errorf("Expected a separate entry for the next edition, got %+v", entries)
+	}
+}
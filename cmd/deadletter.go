@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var maxFailuresFlag = cli.IntFlag{
+	Name:   "max-failures",
+	Value:  3,
+	Usage:  "Number of failed runs after which a conference is parked in the dead-letter queue, 0 never parks",
+	EnvVar: "MAX_FAILURES",
+}
+
+var opsWebhookURLFlag = cli.StringFlag{
+	Name:   "ops-webhook-url",
+	Usage:  "Webhook url notified when a conference is parked, it gets messages in the format of the command",
+	EnvVar: "OPS_WEBHOOK_URL",
+}
+
+// deadLetterQueue counts the failures of conferences across runs and parks
+// the ones which failed too often, so they are not retried on every run.
+type deadLetterQueue struct {
+	filename    string
+	maxFailures int
+	opsURL      string
+	// opsFormat is the --msteams-format of the command, Workflows webhooks
+	// only accept adaptive cards
+	opsFormat   string
+	deadLetters []confs.DeadLetter
+}
+
+func openDeadLetterQueue(c *cli.Context) *deadLetterQueue {
+	filename := confs.DeadLetterFile(c.String("state-file"))
+
+	return &deadLetterQueue{
+		filename:    filename,
+		maxFailures: c.Int("max-failures"),
+		opsURL:      c.String("ops-webhook-url"),
+		opsFormat:   c.String("msteams-format"),
+		deadLetters: confs.LoadDeadLetters(filename),
+	}
+}
+
+func (q *deadLetterQueue) parked() []confs.Conference {
+	conferences := []confs.Conference{}
+	for _, d := range q.deadLetters {
+		if d.Parked {
+			conferences = append(conferences, d.Conference)
+		}
+	}
+
+	return conferences
+}
+
+func (q *deadLetterQueue) find(conference confs.Conference) int {
+	for i, d := range q.deadLetters {
+		if confs.IsSameConference(d.Conference, conference) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// failed counts the failure of the run, transient delivery errors only get
+// here once their retries are exhausted.
+func (q *deadLetterQueue) failed(conference confs.Conference, err error) {
+	i := q.find(conference)
+	if i < 0 {
+		i = len(q.deadLetters)
+		q.deadLetters = append(q.deadLetters, confs.DeadLetter{Conference: conference})
+	}
+
+	d := &q.deadLetters[i]
+	d.Failures++
+	d.LastError = err.Error()
+	if q.maxFailures > 0 && d.Failures >= q.maxFailures && !d.Parked {
+		d.Parked = true
+		q.notify(*d)
+	}
+
+	q.save()
+}
+
+func (q *deadLetterQueue) delivered(conference confs.Conference) {
+	i := q.find(conference)
+	if i < 0 {
+		return
+	}
+
+	q.deadLetters = append(q.deadLetters[:i], q.deadLetters[i+1:]...)
+	q.save()
+}
+
+func (q *deadLetterQueue) save() {
+	if delivery.DryRun {
+		return
+	}
+
+	err := confs.SaveDeadLetters(q.filename, q.deadLetters)
+	if err != nil {
+		log.Printf("Could not save dead-letter queue %s: %s", q.filename, err)
+	}
+}
+
+func (q *deadLetterQueue) notify(d confs.DeadLetter) {
+	if q.opsURL == "" {
+		return
+	}
+
+	text := fmt.Sprintf("Parked %s (%s) in the dead-letter queue after %d failures: %s", d.Name, d.URL, d.Failures, d.LastError)
+	err := postJSON(q.opsURL, newOpsNotice(q.opsFormat, text), "ops webhook")
+	if err != nil {
+		log.Printf("Could not notify ops webhook: %s", err)
+	}
+}
+
+func newOpsNotice(format string, text string) interface{} {
+	if format == "adaptive" {
+		return newMsteamsAdaptiveMessage([]map[string]interface{}{
+			{"type": "TextBlock", "text": text, "wrap": true},
+		})
+	}
+
+	return map[string]string{"text": text}
+}
+
+func DeadLetterCommand() cli.Command {
+	allFlag := cli.BoolFlag{
+		Name:  "all",
+		Usage: "Apply to all parked conferences",
+	}
+
+	return cli.Command{
+		Name:  "dlq",
+		Usage: "manage conferences parked in the dead-letter queue",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "list",
+				Usage:  "list failed and parked conferences",
+				Action: deadLetterListAction,
+				Flags:  []cli.Flag{stateFileFlag},
+			},
+			cli.Command{
+				Name:      "retry",
+				Usage:     "remove conferences from the dead-letter queue so the next run posts them again",
+				ArgsUsage: "[conference url...]",
+				Action:    deadLetterRetryAction,
+				Flags:     []cli.Flag{stateFileFlag, allFlag},
+			},
+			cli.Command{
+				Name:      "drop",
+				Usage:     "remove conferences from the dead-letter queue and mark them as posted",
+				ArgsUsage: "[conference url...]",
+				Action:    deadLetterDropAction,
+				Flags:     []cli.Flag{stateFileFlag, allFlag},
+			},
+		},
+	}
+}
+
+func deadLetterListAction(c *cli.Context) error {
+	deadLetters := confs.LoadDeadLetters(confs.DeadLetterFile(c.String("state-file")))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tFAILURES\tNAME\tSTART\tURL\tLAST ERROR")
+	for _, d := range deadLetters {
+		status := "failing"
+		if d.Parked {
+			status = "parked"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", status, d.Failures, d.Name, d.StartDate, d.URL, d.LastError)
+	}
+
+	return w.Flush()
+}
+
+// takeDeadLetters removes the selected parked conferences from the
+// dead-letter queue and returns them.
+func takeDeadLetters(c *cli.Context) ([]confs.DeadLetter, error) {
+	urls := map[string]bool{}
+	for _, url := range c.Args() {
+		urls[url] = true
+	}
+	if len(urls) == 0 && !c.Bool("all") {
+		return nil, cli.NewExitError("Please provide conference urls or --all", 1)
+	}
+
+	filename := confs.DeadLetterFile(c.String("state-file"))
+	taken, kept := []confs.DeadLetter{}, []confs.DeadLetter{}
+	for _, d := range confs.LoadDeadLetters(filename) {
+		if d.Parked && (c.Bool("all") || urls[d.URL]) {
+			taken = append(taken, d)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+
+	return taken, confs.SaveDeadLetters(filename, kept)
+}
+
+func deadLetterRetryAction(c *cli.Context) error {
+	taken, err := takeDeadLetters(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d conferences will be posted again by the next run\n", len(taken))
+
+	return nil
+}
+
+func deadLetterDropAction(c *cli.Context) error {
+	taken, err := takeDeadLetters(c)
+	if err != nil {
+		return err
+	}
+
+	stateFile := c.String("state-file")
+	processedConferences := confs.LoadState(stateFile)
+	for _, d := range taken {
+		processedConferences = append(processedConferences, confs.ProcessedConference{Conference: d.Conference})
+	}
+
+	fmt.Printf("%d conferences dropped\n", len(taken))
+
+	return confs.SaveState(stateFile, processedConferences)
+}
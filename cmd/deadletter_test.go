@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestFailingConferenceIsParked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	notices := 0
+	ops := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { notices++ }))
+	defer ops.Close()
+
+	date := time.Now().AddDate(0, 0, 10).Format("2006-01-02")
+	conferences := []confs.Conference{
+		confs.Conference{Name: "Go one", URL: "https://go1.com/", StartDate: date, City: "Berlin"},
+		confs.Conference{Name: "Go two", URL: "https://go2.com/", StartDate: date, City: "Berlin"},
+	}
+
+	c := newPushContext(t, "--state-file", dir+"/state.json", "--keep-going", "--max-failures", "2", "--ops-webhook-url", ops.URL)
+	attempts := 0
+	push := func(conference enrichedConference) error {
+		if conference.Name == "Go two" {
+			attempts++
+			return errors.New("message too large")
+		}
+		return nil
+	}
+
+	for run := 0; run < 3; run++ {
+		_ = pushNewConferences(c, conferences, push)
+	}
+
+	if attempts != 2 {
+		t.Errorf("Expected parked conference not to be attempted again, got %d attempts", attempts)
+	}
+	if notices != 1 {
+		t.Errorf("Expected a single ops notice, got %d", notices)
+	}
+
+	deadLetters := confs.LoadDeadLetters(dir + "/state.dlq.json")
+	if len(deadLetters) != 1 || !deadLetters[0].Parked || deadLetters[0].LastError != "message too large" {
+		t.Fatalf("Unexpected dead letters %+v", deadLetters)
+	}
+
+	set := flag.NewFlagSet("drop", flag.ContinueOnError)
+	stateFileFlag.Apply(set)
+	_ = set.Parse([]string{"--state-file", dir + "/state.json", "https://go2.com/"})
+	err = deadLetterDropAction(cli.NewContext(cli.NewApp(), set, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deadLetters := confs.LoadDeadLetters(dir + "/state.dlq.json"); len(deadLetters) != 0 {
+		t.Errorf("Expected dropped conference to leave the dead-letter queue, got %+v", deadLetters)
+	}
+	if state := confs.LoadState(dir + "/state.json"); len(state) != 2 {
+		t.Errorf("Expected dropped conference to be marked as posted, got %+v", state)
+	}
+}
+
+func TestConferenceFailingAfterRetriesIsParked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server, _ := startFlakyServer(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway,
+		http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	defer server.Close()
+
+	date := time.Now().AddDate(0, 0, 10).Format("2006-01-02")
+	conferences := []confs.Conference{confs.Conference{Name: "Go one", URL: "https://go1.com/", StartDate: date, City: "Berlin"}}
+
+	c := newPushContext(t, "--state-file", dir+"/state.json", "--keep-going", "--max-failures", "2")
+	for run := 0; run < 2; run++ {
+		_ = pushNewConferences(c, conferences, func(conference enrichedConference) error {
+			return postJSON(server.URL, map[string]string{"text": conference.Name}, "test")
+		})
+	}
+
+	deadLetters := confs.LoadDeadLetters(dir + "/state.dlq.json")
+	if len(deadLetters) != 1 || !deadLetters[0].Parked {
+		t.Errorf("Expected conference failing with 502 after the retries to be parked, got %+v", deadLetters)
+	}
+}
+
+func TestOpsNoticeUsesAdaptiveCardsForWorkflows(t *testing.T) {
+	var notice map[string]interface{}
+	ops := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&notice)
+	}))
+	defer ops.Close()
+
+	q := &deadLetterQueue{opsURL: ops.URL, opsFormat: "adaptive"}
+	q.notify(confs.DeadLetter{Conference: confs.Conference{Name: "Go two", URL: "https://go2.com/"}, Failures: 3})
+
+	if notice["type"] != "message" || notice["attachments"] == nil || notice["text"] != nil {
+		t.Errorf("Expected an adaptive card message, got %+v", notice)
+	}
+}
+
+func TestDeadLettersOfPastConferencesArePruned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := dir + "/state.dlq.json"
+	err = confs.SaveDeadLetters(filename, []confs.DeadLetter{
+		confs.DeadLetter{Conference: confs.Conference{Name: "Go past", StartDate: time.Now().AddDate(0, 0, -10).Format("2006-01-02")}, Parked: true},
+		confs.DeadLetter{Conference: confs.Conference{Name: "Go next", StartDate: time.Now().AddDate(0, 0, 10).Format("2006-01-02")}, Parked: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deadLetters := confs.LoadDeadLetters(filename); len(deadLetters) != 1 || deadLetters[0].Name != "Go next" {
+		t.Errorf("Expected only the upcoming conference, got %+v", deadLetters)
+	}
+}
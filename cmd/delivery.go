@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// deliveryPolicy controls how often and how fast messages are sent to the
+// destinations.
+type deliveryPolicy struct {
+	Retries        int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Interval is the minimum time between two messages sent to the same url
+	Interval time.Duration
+	// DryRun prints the messages instead of sending them
+	DryRun bool
+}
+
+// deliveryClient sends the messages, its timeout keeps a stalled destination
+// from hanging the run, timed out requests are retried.
+var deliveryClient = &http.Client{Timeout: 30 * time.Second}
+
+var delivery = deliveryPolicy{
+	Retries:        4,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Interval:       time.Second,
+}
+
+var (
+	lastDelivery      = map[string]time.Time{}
+	lastDeliveryMutex sync.Mutex
+)
+
+// deliveryError is returned when a message could not be delivered, permanent
+// errors are not going to succeed when the same message is sent again.
+type deliveryError struct {
+	Service    string
+	StatusCode int
+	RetryAfter time.Duration
+	Permanent  bool
+	Err        error
+}
+
+func (e *deliveryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Got error when sending message to %s: %s", e.Service, e.Err)
+	}
+
+	return fmt.Sprintf("Got response code %d when sending message to %s", e.StatusCode, e.Service)
+}
+
+func isPermanentError(err error) bool {
+	e, ok := err.(*deliveryError)
+	return ok && e.Permanent
+}
+
+// sendRequest sends the request retrying transient failures with exponential
+// backoff, the response is decoded into result unless it is nil.
+func sendRequest(req *http.Request, result interface{}, service string) error {
+	if delivery.DryRun {
+		return printRequest(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			req.Body = body
+		}
+
+		waitForDeliveryInterval(req.URL.String())
+
+		err := doRequest(req, result, service)
+		if err == nil || isPermanentError(err) || attempt >= delivery.Retries {
+			return err
+		}
+
+		time.Sleep(delivery.backoff(attempt, err))
+	}
+}
+
+func doRequest(req *http.Request, result interface{}, service string) error {
+	resp, err := deliveryClient.Do(req)
+	if err != nil {
+		return &deliveryError{Service: service, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &deliveryError{
+			Service:    service,
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Permanent:  !isTransientStatus(resp.StatusCode),
+		}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		// The message was delivered, sending it again would duplicate it
+		log.Printf("Could not read the response of %s: %s", service, err)
+	}
+
+	return nil
+}
+
+func isTransientStatus(statusCode int) bool {
+	return statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500 && statusCode != http.StatusNotImplemented
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// backoff returns the exponential backoff with jitter for the attempt, or the
+// time the server asked for with Retry-After, both at most MaxBackoff.
+func (p deliveryPolicy) backoff(attempt int, err error) time.Duration {
+	if e, ok := err.(*deliveryError); ok && e.RetryAfter > 0 {
+		if e.RetryAfter > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+		return e.RetryAfter
+	}
+
+	backoff := p.InitialBackoff << uint(attempt)
+	if backoff > p.MaxBackoff || backoff <= 0 {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// waitForDeliveryInterval rate limits the messages sent to the same url. The
+// time slot is reserved under the lock but waited for outside of it, so other
+// urls are not held up.
+func waitForDeliveryInterval(url string) {
+	lastDeliveryMutex.Lock()
+	next := time.Now()
+	if last, found := lastDelivery[url]; found && last.Add(delivery.Interval).After(next) {
+		next = last.Add(delivery.Interval)
+	}
+	lastDelivery[url] = next
+	lastDeliveryMutex.Unlock()
+
+	time.Sleep(time.Until(next))
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	delivery = deliveryPolicy{
+		Retries:        2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	}
+	enrichment.Timeout = 100 * time.Millisecond
+	fetching.AllowPrivate = true // The test servers listen on localhost
+
+	os.Exit(m.Run())
+}
+
+func startFlakyServer(statusCodes ...int) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls < len(statusCodes) {
+			if statusCodes[calls] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(statusCodes[calls])
+		}
+		calls++
+	}))
+
+	return server, &calls
+}
+
+func TestPostJSONRetriesTransientErrors(t *testing.T) {
+	server, calls := startFlakyServer(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+
+	err := postJSON(server.URL, map[string]string{"text": "hi"}, "test")
+	if err != nil {
+		t.Errorf("Expected message to be delivered after retries, got %s", err)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestPostJSONGivesUpAfterRetries(t *testing.T) {
+	server, calls := startFlakyServer(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	defer server.Close()
+
+	err := postJSON(server.URL, map[string]string{"text": "hi"}, "test")
+	if err == nil || isPermanentError(err) {
+		t.Errorf("Expected transient error, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestPostJSONDoesNotRetryPermanentErrors(t *testing.T) {
+	server, calls := startFlakyServer(http.StatusBadRequest)
+	defer server.Close()
+
+	err := postJSON(server.URL, map[string]string{"text": "hi"}, "test")
+	if !isPermanentError(err) {
+		t.Errorf("Expected permanent error, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected a single call, got %d", *calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("3"); d != 3*time.Second {
+		t.Errorf("Expected 3s, got %s", d)
+	}
+	if d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); d <= 0 || d > time.Minute {
+		t.Errorf("Expected up to a minute, got %s", d)
+	}
+}
+
+func TestBackoffCapsRetryAfter(t *testing.T) {
+	policy := deliveryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute}
+	if d := policy.backoff(0, &deliveryError{RetryAfter: 24 * time.Hour}); d != time.Minute {
+		t.Errorf("Expected Retry-After to be capped at a minute, got %s", d)
+	}
+	if d := policy.backoff(0, &deliveryError{RetryAfter: 3 * time.Second}); d != 3*time.Second {
+		t.Errorf("Expected Retry-After of 3s, got %s", d)
+	}
+}
+
+func TestWaitForDeliveryIntervalDoesNotBlockOtherURLs(t *testing.T) {
+	interval := delivery.Interval
+	delivery.Interval = time.Second
+	defer func() { delivery.Interval = interval }()
+
+	waitForDeliveryInterval("http://127.0.0.1:1/slow")
+	go waitForDeliveryInterval("http://127.0.0.1:1/slow")
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	waitForDeliveryInterval("http://127.0.0.1:1/other")
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("Expected other url not to wait, waited %s", d)
+	}
+}
+
+func TestPostJSONRetriesStalledDestinations(t *testing.T) {
+	timeout := deliveryClient.Timeout
+	deliveryClient.Timeout = 20 * time.Millisecond
+	defer func() { deliveryClient.Timeout = timeout }()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	err := postJSON(server.URL, map[string]string{"text": "hi"}, "test")
+	if err == nil || isPermanentError(err) {
+		t.Errorf("Expected transient timeout error, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestSendJSONAcceptsUnreadableSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>ok</html>"))
+	}))
+	defer server.Close()
+
+	var result map[string]interface{}
+	err := sendJSON(http.MethodPost, server.URL, nil, map[string]string{"text": "hi"}, &result, "test")
+	if err != nil {
+		t.Errorf("Expected delivered message with unreadable response, got %s", err)
+	}
+}
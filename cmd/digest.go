@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var digestFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:   "digest",
+		Usage:  "Post all new conferences of a run in a single message",
+		EnvVar: "DIGEST",
+	},
+	cli.StringFlag{
+		Name:   "digest-group",
+		Value:  "month",
+		Usage:  "Group digest conferences by month, country or cfp closing date",
+		EnvVar: "DIGEST_GROUP",
+	},
+}
+
+// errDigestTooLarge is returned by digest renderers when the conferences do
+// not fit into a single message.
+var errDigestTooLarge = errors.New("Digest does not fit into a single message")
+
+type conferenceGroup struct {
+	Title       string
+	Conferences []confs.Conference
+}
+
+type digestMessage struct {
+	Conferences []confs.Conference
+	Payload     interface{}
+}
+
+type digestRenderer func(title string, groups []conferenceGroup) (interface{}, error)
+
+// groupConferences groups the conferences by month, country or CFP end date
+// keeping them sorted by date within each group.
+func groupConferences(conferences []confs.Conference, groupBy string) ([]conferenceGroup, error) {
+	var key func(confs.Conference) string
+	switch groupBy {
+	case "month":
+		key = func(c confs.Conference) string {
+			if len(c.StartDate) < 7 {
+				return c.StartDate
+			}
+			return c.StartDate[:7]
+		}
+	case "country":
+		key = func(c confs.Conference) string { return c.Country }
+	case "cfp":
+		key = func(c confs.Conference) string { return "CFP closes " + c.CFPEndDate }
+	default:
+		return nil, fmt.Errorf("Invalid digest group %s", groupBy)
+	}
+
+	groups := []conferenceGroup{}
+	index := map[string]int{}
+	for _, c := range confs.SortConferences(conferences, confs.ByStartDate) {
+		k := key(c)
+		i, found := index[k]
+		if !found {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, conferenceGroup{Title: k})
+		}
+		groups[i].Conferences = append(groups[i].Conferences, c)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Title < groups[j].Title })
+
+	if groupBy == "month" {
+		for i := range groups {
+			month, err := time.Parse("2006-01", groups[i].Title)
+			if err == nil {
+				groups[i].Title = month.Format("January 2006")
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// splitDigest renders the conferences into as few messages as possible, every
+// payload stays below limit bytes of JSON.
+func splitDigest(title string, conferences []confs.Conference, groupBy string, limit int, render digestRenderer) ([]digestMessage, error) {
+	tryRender := func(conferences []confs.Conference) (interface{}, error) {
+		groups, err := groupConferences(conferences, groupBy)
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := render(title, groups)
+		if err != nil {
+			return nil, err
+		}
+
+		payloadString, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(payloadString) > limit {
+			return nil, errDigestTooLarge
+		}
+
+		return payload, nil
+	}
+
+	messages := []digestMessage{}
+	current := digestMessage{}
+	for _, c := range confs.SortConferences(conferences, confs.ByStartDate) {
+		candidate := append(append([]confs.Conference{}, current.Conferences...), c)
+		payload, err := tryRender(candidate)
+		if err == errDigestTooLarge && len(current.Conferences) > 0 {
+			messages = append(messages, current)
+			candidate = []confs.Conference{c}
+			payload, err = tryRender(candidate)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		current = digestMessage{Conferences: candidate, Payload: payload}
+	}
+	if len(current.Conferences) > 0 {
+		messages = append(messages, current)
+	}
+
+	return messages, nil
+}
+
+// pushDigest sends the new conferences as digest messages, the conferences of
+// a message are recorded in the state file together once it was sent.
+func pushDigest(c *cli.Context, topic string, conferences []confs.Conference, limit int, render digestRenderer, send func(interface{}) error) error {
+	if c.String("template") != "" {
+		return fmt.Errorf("Digest messages use the built-in layout, --template cannot be combined with --digest")
+	}
+
+	stateFile := c.String("state-file")
+	conferences, processedConferences, err := loadNewConferences(c, conferences)
+	if err != nil {
+		return err
+	}
+	if len(conferences) == 0 {
+		return nil
+	}
+
+	title := fmt.Sprintf("New %s conferences", topic)
+	messages, err := splitDigest(title, conferences, c.String("digest-group"), limit, render)
+	if err != nil {
+		return err
+	}
+
+	report := newDeliveryReport(c)
+	for _, message := range messages {
+		err := send(message.Payload)
+		if err != nil {
+			keepGoing := false
+			for _, conference := range message.Conferences {
+				keepGoing = report.fail(conference, err)
+			}
+			if keepGoing {
+				continue
+			}
+
+			return err
+		}
+
+		for _, conference := range message.Conferences {
+			report.success(conference)
+			processedConferences = append(processedConferences, confs.ProcessedConference{Conference: conference})
+		}
+		err = saveState(stateFile, processedConferences)
+		if err != nil {
+			return err
+		}
+	}
+
+	return report.finish(c)
+}
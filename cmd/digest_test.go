@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"flag"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var digestConferences = []confs.Conference{
+	confs.Conference{Name: "Go three", URL: "https://go3.com/", StartDate: "2019-09-02", EndDate: "2019-09-02", City: "Berlin", Country: "Germany"},
+	confs.Conference{Name: "Go one", URL: "https://go1.com/", StartDate: "2019-08-20", EndDate: "2019-08-20", City: "Mariupol", Country: "Ukraine"},
+	confs.Conference{Name: "Go two", URL: "https://go2.com/", StartDate: "2019-08-21", EndDate: "2019-08-22", City: "Munich", Country: "Germany"},
+}
+
+func TestGroupConferencesByMonth(t *testing.T) {
+	groups, err := groupConferences(digestConferences, "month")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 2 || groups[0].Title != "August 2019" || groups[1].Title != "September 2019" {
+		t.Fatalf("Unexpected groups %+v", groups)
+	}
+	if groups[0].Conferences[0].Name != "Go one" || groups[0].Conferences[1].Name != "Go two" {
+		t.Errorf("Expected conferences sorted by date, got %+v", groups[0].Conferences)
+	}
+}
+
+func TestGroupConferencesByCountry(t *testing.T) {
+	groups, err := groupConferences(digestConferences, "country")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 2 || groups[0].Title != "Germany" || len(groups[0].Conferences) != 2 || groups[1].Title != "Ukraine" {
+		t.Errorf("Unexpected groups %+v", groups)
+	}
+}
+
+func TestSplitDigestRespectsLimit(t *testing.T) {
+	messages, err := splitDigest("New golang conferences", digestConferences, "month", 1000, func(title string, groups []conferenceGroup) (interface{}, error) {
+		return newSlackDigestMessage(title, groups, "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || len(messages[0].Conferences) != 3 {
+		t.Fatalf("Expected a single digest message, got %+v", messages)
+	}
+
+	messages, err = splitDigest("New golang conferences", digestConferences, "month", 200, newMsteamsDigestMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) < 2 {
+		t.Fatalf("Expected digest to be split, got %+v", messages)
+	}
+	count := 0
+	for _, message := range messages {
+		count += len(message.Conferences)
+	}
+	if count != 3 {
+		t.Errorf("Expected all conferences in the digest, got %d", count)
+	}
+}
+
+func TestPushDigestRejectsTemplate(t *testing.T) {
+	set := flag.NewFlagSet("slack", flag.ContinueOnError)
+	cli.StringFlag{Name: "template"}.Apply(set)
+	err := set.Parse([]string{"--template", "custom.tmpl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := 0
+	err = pushDigest(cli.NewContext(cli.NewApp(), set, nil), "golang", digestConferences, slackDigestLimit, newMsteamsDigestMessage, func(payload interface{}) error {
+		sent++
+		return nil
+	})
+	if err == nil || sent != 0 {
+		t.Errorf("Expected digest with custom template to be rejected, got %v after %d messages", err, sent)
+	}
+}
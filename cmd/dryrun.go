@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+// dryRunOutput receives the requests and state changes which would have been
+// made with --dry-run.
+var dryRunOutput io.Writer = os.Stdout
+
+// dryRunStates keeps the proposed state per state file, it is printed as a
+// diff against the state file once the command finished.
+var dryRunStates = map[string][]confs.ProcessedConference{}
+
+// printRequest prints the request instead of sending it, JSON payloads are
+// printed byte for byte as they would be sent and other payloads only by size.
+func printRequest(req *http.Request) error {
+	body := []byte{}
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+	}
+
+	fmt.Fprintf(dryRunOutput, "%s %s\n", req.Method, req.URL)
+
+	if json.Valid(body) {
+		fmt.Fprintf(dryRunOutput, "%s\n\n", body)
+	} else {
+		fmt.Fprintf(dryRunOutput, "<%d bytes of %s>\n\n", len(body), req.Header.Get("Content-Type"))
+	}
+
+	return nil
+}
+
+// saveState saves the state file, or keeps it as the proposed state with
+// --dry-run.
+func saveState(filename string, processedConferences []confs.ProcessedConference) error {
+	if delivery.DryRun {
+		dryRunStates[filename] = processedConferences
+		return nil
+	}
+
+	return confs.SaveState(filename, processedConferences)
+}
+
+// printStateDiffs prints the difference between the state files and the
+// proposed states.
+func printStateDiffs() {
+	filenames := []string{}
+	for filename := range dryRunStates {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		printStateDiff(filename, confs.LoadState(filename), dryRunStates[filename])
+	}
+}
+
+func printStateDiff(filename string, current []confs.ProcessedConference, proposed []confs.ProcessedConference) {
+	removed := subtractState(current, proposed)
+	added := subtractState(proposed, current)
+	if len(removed) == 0 && len(added) == 0 {
+		fmt.Fprintf(dryRunOutput, "State file %s would not change\n", filename)
+		return
+	}
+
+	fmt.Fprintf(dryRunOutput, "State file %s would change:\n", filename)
+	for _, p := range removed {
+		fmt.Fprintf(dryRunOutput, "- %s\n", formatStateEntry(p))
+	}
+	for _, p := range added {
+		fmt.Fprintf(dryRunOutput, "+ %s\n", formatStateEntry(p))
+	}
+}
+
+// subtractState returns the entries of a which are not in b.
+func subtractState(a []confs.ProcessedConference, b []confs.ProcessedConference) []confs.ProcessedConference {
+	result := []confs.ProcessedConference{}
+	for _, p := range a {
+		found := false
+		for _, q := range b {
+			if p == q {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+func formatStateEntry(p confs.ProcessedConference) string {
+	entry := fmt.Sprintf("%s %s (%s, %s)", p.StartDate, p.Name, p.URL, formatLocation(p.Conference))
+	if p.CFPReminded {
+		entry += " CFP reminded"
+	}
+
+	return entry
+}
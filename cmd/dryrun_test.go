@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestDryRunPrintsPayloadsAndStateDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dryrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stateFile := dir + "/state.json"
+	var output bytes.Buffer
+	dryRunOutput = &output
+	delivery.DryRun = true
+	defer func() {
+		dryRunOutput = os.Stdout
+		delivery.DryRun = false
+		dryRunStates = map[string][]confs.ProcessedConference{}
+	}()
+
+	conference := confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1", StartDate: "2099-08-20", EndDate: "2099-08-20", City: "Berlin", Country: "Germany"}
+	err = pushNewConferences(newPushContext(t, "--state-file", stateFile), []confs.Conference{conference}, func(conference enrichedConference) error {
+		return postJSON("http://127.0.0.1:1/hook", map[string]string{"text": conference.Name}, "test")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	printStateDiffs()
+
+	expected := "POST http://127.0.0.1:1/hook\n{\"text\":\"Go one\"}\n\n" +
+		"State file " + stateFile + " would change:\n" +
+		"+ 2099-08-20 Go one (http://127.0.0.1:1/go1, Berlin, Germany 🇩🇪)\n"
+	if output.String() != expected {
+		t.Errorf("Unexpected dry run output %q", output.String())
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the state file not to be written, got %v", err)
+	}
+}
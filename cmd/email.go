@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func EmailCommand() cli.Command {
+	return cli.Command{
+		Name:   "email",
+		Usage:  "send new conferences as an email digest",
+		Action: wrapAction(emailAction),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "smtp-host",
+				Usage:  "SMTP server host",
+				EnvVar: "SMTP_HOST",
+			},
+			cli.IntFlag{
+				Name:   "smtp-port",
+				Value:  587,
+				Usage:  "SMTP server port",
+				EnvVar: "SMTP_PORT",
+			},
+			cli.StringFlag{
+				Name:   "smtp-username",
+				Usage:  "SMTP username, authentication is skipped when empty",
+				EnvVar: "SMTP_USERNAME",
+			},
+			cli.StringFlag{
+				Name:   "smtp-password",
+				Usage:  "SMTP password",
+				EnvVar: "SMTP_PASSWORD",
+			},
+			cli.StringFlag{
+				Name:   "email-from",
+				Usage:  "Sender address",
+				EnvVar: "EMAIL_FROM",
+			},
+			cli.StringSliceFlag{
+				Name:   "email-to",
+				Usage:  "Recipient addresses",
+				EnvVar: "EMAIL_TO",
+			},
+			stateFileFlag,
+			orderFlag,
+			maxPerRunFlag,
+		},
+	}
+}
+
+type emailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func emailAction(topic string, conferences []confs.Conference, c *cli.Context) error {
+	config := emailConfig{
+		Host:     c.String("smtp-host"),
+		Port:     c.Int("smtp-port"),
+		Username: c.String("smtp-username"),
+		Password: c.String("smtp-password"),
+		From:     c.String("email-from"),
+		To:       c.StringSlice("email-to"),
+	}
+	if config.Host == "" {
+		return fmt.Errorf("Please provide SMTP server host")
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return fmt.Errorf("Please provide sender and recipient addresses")
+	}
+
+	stateFile := c.String("state-file")
+	conferences, processedConferences, err := loadNewConferences(c, conferences)
+	if err != nil {
+		return err
+	}
+	if len(conferences) == 0 {
+		return nil
+	}
+
+	ogs := []*opengraph.OpenGraph{}
+	for _, conference := range enrichConferences(context.Background(), conferences) {
+		ogs = append(ogs, conference.Opengraph)
+	}
+
+	message, err := newEmailMessage(config, topic, conferences, ogs)
+	if err != nil {
+		return err
+	}
+
+	err = sendEmail(config, message)
+	if err != nil {
+		return err
+	}
+
+	for _, conference := range conferences {
+		processedConferences = append(processedConferences, confs.ProcessedConference{Conference: conference})
+	}
+
+	return saveState(stateFile, processedConferences)
+}
+
+var emailHTMLTemplate = template.Must(template.New("email").Funcs(template.FuncMap{
+	"body": func(c confs.Conference, og *opengraph.OpenGraph) template.HTML {
+		return template.HTML(formatHTMLBody(c, og))
+	},
+}).Parse(`<html>
+<body>
+{{- range $i, $c := .Conferences }}
+<h3><a href="{{ $c.URL }}">{{ $c.Name }}</a></h3>
+{{ body $c (index $.Opengraphs $i) }}
+{{- end }}
+<p><a href="https://confs.tech/{{ .Topic }}">https://confs.tech/{{ .Topic }}</a></p>
+</body>
+</html>
+`))
+
+func newEmailMessage(config emailConfig, topic string, conferences []confs.Conference, ogs []*opengraph.OpenGraph) ([]byte, error) {
+	var text bytes.Buffer
+	for _, c := range conferences {
+		fmt.Fprintf(&text, "%s\r\n%s\r\n%s・%s\r\n\r\n", c.Name, c.URL, formatLocation(c), formatDateRange(c))
+	}
+	fmt.Fprintf(&text, "https://confs.tech/%s\r\n", topic)
+
+	var html bytes.Buffer
+	err := emailHTMLTemplate.Execute(&html, map[string]interface{}{
+		"Topic":       topic,
+		"Conferences": conferences,
+		"Opengraphs":  ogs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=UTF-8", text.Bytes()},
+		{"text/html; charset=UTF-8", html.Bytes()},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write(part.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", config.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&message, "Subject: %d new %s tech conferences\r\n", len(conferences), topic)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// sendEmail delivers the message, smtp.SendMail upgrades the connection with
+// STARTTLS whenever the server supports it.
+func sendEmail(config emailConfig, message []byte) error {
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	if delivery.DryRun {
+		fmt.Fprintf(dryRunOutput, "SMTP %s from %s to %s\n%s\n\n", addr, config.From, strings.Join(config.To, ", "), message)
+		return nil
+	}
+
+	return smtp.SendMail(addr, auth, config.From, config.To, message)
+}
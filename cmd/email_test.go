@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/otiai10/opengraph"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+// startSMTPServer accepts a single SMTP session and sends the received DATA to
+// the returned channel.
+func startSMTPServer(t *testing.T) (string, int, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not start SMTP server: %s", err)
+	}
+
+	messages := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case command == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				messages <- data.String()
+				reply("250 ok")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+
+	return addr.IP.String(), addr.Port, messages
+}
+
+func TestSendEmail(t *testing.T) {
+	host, port, messages := startSMTPServer(t)
+	config := emailConfig{
+		Host: host,
+		Port: port,
+		From: "confs@example.com",
+		To:   []string{"team@example.com"},
+	}
+
+	conferences := []confs.Conference{
+		confs.Conference{
+			Name:      "Go one",
+			URL:       "https://go1.com/",
+			StartDate: "2019-08-20",
+			EndDate:   "2019-08-20",
+			City:      "Berlin",
+			Country:   "Germany",
+		},
+	}
+	og := opengraph.New("https://go1.com/")
+	og.Description = "The first Go conference"
+
+	message, err := newEmailMessage(config, "golang", conferences, []*opengraph.OpenGraph{og})
+	if err != nil {
+		t.Fatalf("Got error when building email: %s", err)
+	}
+
+	err = sendEmail(config, message)
+	if err != nil {
+		t.Fatalf("Got error when sending email: %s", err)
+	}
+
+	received := <-messages
+	for _, expected := range []string{
+		"Subject: 1 new golang tech conferences",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Type: text/html; charset=UTF-8",
+		"<h3><a href=\"https://go1.com/\">Go one</a></h3>",
+		"<p>The first Go conference</p>",
+		"Berlin, Germany 🇩🇪・2019-08-20",
+	} {
+		if !strings.Contains(received, expected) {
+			t.Errorf("Expected email to contain '%s', got:\n%s", expected, received)
+		}
+	}
+}
+
+func TestSendEmailFailsWithoutServer(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	err := sendEmail(emailConfig{Host: "127.0.0.1", Port: port, From: "a@example.com", To: []string{"b@example.com"}}, []byte("x"))
+	if err == nil {
+		t.Errorf("Expected error when SMTP server is not reachable on port %d", port)
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/otiai10/opengraph"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+// enrichmentPolicy controls how the opengraph data of the conference websites
+// is fetched.
+type enrichmentPolicy struct {
+	Workers int
+	// Timeout is the maximum time a single website is waited for
+	Timeout time.Duration
+}
+
+var enrichment = enrichmentPolicy{
+	Workers: 8,
+	Timeout: 10 * time.Second,
+}
+
+// enrichedConference is a conference together with the opengraph data of its
+// website, completed from the other metadata of the page.
+type enrichedConference struct {
+	confs.Conference
+	Opengraph *opengraph.OpenGraph
+	Metadata  pageMetadata
+}
+
+// enrichConferences fetches the opengraph data of the conferences with a pool
+// of workers, the results are in the order of the conferences. Conferences
+// which could not be fetched before the context is done get empty data.
+func enrichConferences(ctx context.Context, conferences []confs.Conference) []enrichedConference {
+	enriched := make([]enrichedConference, len(conferences))
+
+	workers := enrichment.Workers
+	if workers > len(conferences) {
+		workers = len(conferences)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				og, metadata := fetchOpengraph(ctx, conferences[i].URL)
+				enriched[i] = enrichedConference{
+					Conference: conferences[i],
+					Opengraph:  og,
+					Metadata:   metadata,
+				}
+			}
+		}()
+	}
+
+	for i := range conferences {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return enriched
+}
+
+func fetchOpengraph(ctx context.Context, url string) (*opengraph.OpenGraph, pageMetadata) {
+	if entry, found := ogCache.get(url); found && !images.isMissingThumbnail(entry.Opengraph) {
+		if entry.Failed {
+			return opengraph.New(url), pageMetadata{}
+		}
+		return entry.Opengraph, entry.Metadata
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, enrichment.Timeout)
+	defer cancel()
+
+	og, metadata, err := fetchPage(fetching.client(fetchCtx), url)
+	if ctx.Err() == nil {
+		ogCache.put(url, og, metadata, err) // Not caching fetches cancelled by the caller
+	}
+	if err != nil {
+		return opengraph.New(url), pageMetadata{} // Ignoring the error, opengraph data is not critical
+	}
+
+	return og, metadata
+}
+
+// fetchPage parses the opengraph tags of the page and fills the missing ones
+// from its other metadata.
+func fetchPage(client *http.Client, url string) (*opengraph.OpenGraph, pageMetadata, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, pageMetadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, pageMetadata{}, fmt.Errorf("Got response code %d when fetching %s", resp.StatusCode, url)
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if contentType != "text/html" && contentType != "application/xhtml+xml" {
+		return nil, pageMetadata{}, fmt.Errorf("Invalid page type %s", contentType)
+	}
+
+	page, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, pageMetadata{}, err
+	}
+
+	og := opengraph.New(url)
+	err = og.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, pageMetadata{}, err
+	}
+
+	// Relative urls are resolved against the page reached after redirects
+	pageURL := resp.Request.URL.String()
+	metadata, candidates := extractMetadata(page, pageURL)
+	completeOpengraph(og, metadata)
+
+	for _, candidate := range candidates {
+		og.Image = append(og.Image, &opengraph.Image{URL: candidate})
+	}
+	og.Image = images.validateImages(client, pageURL, og.Image)
+	metadata.Image = ""
+	if len(og.Image) > 0 {
+		metadata.Image = og.Image[0].URL
+	}
+
+	return og, metadata, nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestEnrichConferencesKeepsOrderAndTimesOut(t *testing.T) {
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer hanging.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html></html>`))
+	}))
+	defer fast.Close()
+
+	conferences := []confs.Conference{
+		confs.Conference{Name: "Go hanging", URL: hanging.URL},
+		confs.Conference{Name: "Go fast", URL: fast.URL},
+		confs.Conference{Name: "Go down", URL: "http://127.0.0.1:1/down"},
+	}
+
+	start := time.Now()
+	enriched := enrichConferences(context.Background(), conferences)
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Expected hanging website to time out, took %s", time.Since(start))
+	}
+
+	if len(enriched) != len(conferences) {
+		t.Fatalf("Expected %d enriched conferences, got %d", len(conferences), len(enriched))
+	}
+	for i, e := range enriched {
+		if e.Conference != conferences[i] || e.Opengraph == nil {
+			t.Errorf("Expected %s at %d with opengraph data, got %+v", conferences[i].Name, i, e)
+		}
+	}
+}
+
+func TestEnrichConferencesStopsWhenCancelled(t *testing.T) {
+	ogCache = openOpengraphCache("unused.json", time.Hour, time.Hour)
+	defer func() { ogCache = nil }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	enriched := enrichConferences(ctx, []confs.Conference{confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1"}})
+	if len(enriched) != 1 || enriched[0].Opengraph == nil {
+		t.Fatalf("Expected empty opengraph data, got %+v", enriched)
+	}
+	if _, found := ogCache.get("http://127.0.0.1:1/go1"); found {
+		t.Errorf("Expected cancelled fetch not to be cached")
+	}
+}
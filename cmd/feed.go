@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/otiai10/opengraph"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+// feedRenderer renders the feed, the conferences are in the order of its items.
+type feedRenderer func(feed *feeds.Feed, topic string, conferences []confs.Conference) (string, error)
+
+var feedRenderers = map[string]feedRenderer{
+	"atom": renderAtomFeed,
+	"rss":  renderRSSFeed,
+	"json": renderJSONFeed,
+}
+
+func generateFeed(ctx context.Context, topic string, conferences []confs.Conference, entries []confs.FeedEntry, format string) (string, error) {
+	render, found := feedRenderers[format]
+	if !found {
+		return "", fmt.Errorf("Invalid feed format %s", format)
+	}
+
+	return render(newFeed(ctx, topic, conferences, entries), topic, conferences)
+}
+
+func newFeed(ctx context.Context, topic string, conferences []confs.Conference, entries []confs.FeedEntry) *feeds.Feed {
+	feed := &feeds.Feed{
+		Title:   topic + " tech conferences",
+		Link:    &feeds.Link{Href: fmt.Sprintf("https://confs.tech/%s", topic)},
+		Author:  &feeds.Author{Name: "https://confs.tech/"},
+		Created: time.Now(),
+	}
+
+	items := []*feeds.Item{}
+	for _, enriched := range enrichConferences(ctx, conferences) {
+		c, og := enriched.Conference, enriched.Opengraph
+
+		item := &feeds.Item{
+			Title:       c.Name,
+			Link:        &feeds.Link{Href: c.URL},
+			Id:          formatTagURI(c),
+			Description: formatHTMLBody(c, og),
+			Created:     feed.Created,
+			Enclosure:   newFeedEnclosure(og),
+		}
+		if i := findFeedEntry(entries, c); i >= 0 {
+			item.Created = entries[i].FirstSeen
+			item.Updated = entries[i].Updated
+		}
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+
+		items = append(items, item)
+	}
+
+	feed.Items = items
+
+	return feed
+}
+
+// newFeedEnclosure returns the opengraph image as enclosure, its length is
+// unknown so it is 0 as recommended for RSS.
+func newFeedEnclosure(og *opengraph.OpenGraph) *feeds.Enclosure {
+	if len(og.Image) == 0 || og.Image[0].URL == "" {
+		return nil
+	}
+
+	imageType := og.Image[0].Type
+	if imageType == "" {
+		if u, err := url.Parse(og.Image[0].URL); err == nil {
+			imageType = mime.TypeByExtension(path.Ext(u.Path))
+		}
+	}
+	if !strings.HasPrefix(imageType, "image/") {
+		return nil
+	}
+
+	return &feeds.Enclosure{Url: og.Image[0].URL, Type: imageType, Length: "0"}
+}
+
+func formatFeedCategories(topic string, c confs.Conference) []string {
+	categories := []string{topic}
+	if c.Country != "" {
+		categories = append(categories, c.Country)
+	}
+
+	return categories
+}
+
+type atomCategory struct {
+	XMLName xml.Name `xml:"category"`
+	Term    string   `xml:"term,attr"`
+}
+
+// atomEntry adds the categories the generic feeds.Item does not support.
+type atomEntry struct {
+	*feeds.AtomEntry
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomFeed struct {
+	*feeds.AtomFeed
+	Entries []*atomEntry `xml:"entry"`
+}
+
+func renderAtomFeed(feed *feeds.Feed, topic string, conferences []confs.Conference) (string, error) {
+	atom := (&feeds.Atom{Feed: feed}).AtomFeed()
+
+	out := &atomFeed{AtomFeed: atom}
+	for i, entry := range atom.Entries {
+		categories := []atomCategory{}
+		for _, category := range formatFeedCategories(topic, conferences[i]) {
+			categories = append(categories, atomCategory{Term: category})
+		}
+		out.Entries = append(out.Entries, &atomEntry{AtomEntry: entry, Categories: categories})
+	}
+
+	return renderFeedXML(out)
+}
+
+// rssItem adds the categories the generic feeds.Item does not support.
+type rssItem struct {
+	*feeds.RssItem
+	Categories []string `xml:"category"`
+}
+
+type rssChannel struct {
+	*feeds.RssFeed
+	Items []*rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	Channel          *rssChannel
+}
+
+func renderRSSFeed(feed *feeds.Feed, topic string, conferences []confs.Conference) (string, error) {
+	rss := (&feeds.Rss{Feed: feed}).RssFeed()
+
+	channel := &rssChannel{RssFeed: rss}
+	for i, item := range rss.Items {
+		channel.Items = append(channel.Items, &rssItem{RssItem: item, Categories: formatFeedCategories(topic, conferences[i])})
+	}
+
+	return renderFeedXML(&rssFeed{
+		Version:          "2.0",
+		ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+		Channel:          channel,
+	})
+}
+
+func renderFeedXML(feed interface{}) (string, error) {
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header[:len(xml.Header)-1] + string(data), nil
+}
+
+type jsonFeedLocation struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+// jsonFeedConference is the _conference extension of the JSON Feed items, so
+// the conference data does not have to be parsed out of the HTML.
+type jsonFeedConference struct {
+	About      string           `json:"about"`
+	Name       string           `json:"name"`
+	StartDate  string           `json:"start_date"`
+	EndDate    string           `json:"end_date"`
+	Location   jsonFeedLocation `json:"location"`
+	CFPURL     string           `json:"cfp_url,omitempty"`
+	CFPEndDate string           `json:"cfp_end_date,omitempty"`
+	Twitter    string           `json:"twitter,omitempty"`
+}
+
+type jsonFeedItem struct {
+	*feeds.JSONItem
+	Conference jsonFeedConference `json:"_conference"`
+}
+
+type jsonFeed struct {
+	*feeds.JSONFeed
+	Items []*jsonFeedItem `json:"items"`
+}
+
+func renderJSONFeed(feed *feeds.Feed, topic string, conferences []confs.Conference) (string, error) {
+	jsonFeedData := (&feeds.JSON{Feed: feed}).JSONFeed()
+
+	out := &jsonFeed{JSONFeed: jsonFeedData, Items: []*jsonFeedItem{}}
+	for i, item := range jsonFeedData.Items {
+		c := conferences[i]
+		item.Tags = formatFeedCategories(topic, c)
+		out.Items = append(out.Items, &jsonFeedItem{
+			JSONItem: item,
+			Conference: jsonFeedConference{
+				About:      "https://github.com/flix-tech/confs.tech.push",
+				Name:       c.Name,
+				StartDate:  c.StartDate,
+				EndDate:    c.EndDate,
+				Location:   jsonFeedLocation{City: c.City, Country: c.Country},
+				CFPURL:     c.CFPUrl,
+				CFPEndDate: c.CFPEndDate,
+				Twitter:    formatTwitterURL(c),
+			},
+		})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/otiai10/opengraph"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var feedConferences = []confs.Conference{
+	confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1", StartDate: "2019-08-20", EndDate: "2019-08-21", City: "Berlin", Country: "Germany", CFPEndDate: "2019-06-01", Twitter: "@goone"},
+}
+
+func TestFeedFormatsHaveCategories(t *testing.T) {
+	for format, expected := range map[string]string{
+		"atom": `<category term="golang"></category>`,
+		"rss":  `<category>Germany</category>`,
+	} {
+		feed, err := generateFeed(context.Background(), "golang", feedConferences, []confs.FeedEntry{}, format)
+		if err != nil {
+			t.Fatalf("Got error when generating %s feed: %s", format, err)
+		}
+		if !strings.Contains(feed, expected) || strings.Count(feed, "<title>Go one</title>") != 1 {
+			t.Errorf("Expected %s once in %s feed %s", expected, format, feed)
+		}
+	}
+}
+
+func TestJSONFeedHasConferenceExtension(t *testing.T) {
+	feed, err := generateFeed(context.Background(), "golang", feedConferences, []confs.FeedEntry{}, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed struct {
+		Version string
+		Items   []struct {
+			ID         string             `json:"id"`
+			Tags       []string           `json:"tags"`
+			Conference jsonFeedConference `json:"_conference"`
+		}
+	}
+	err = json.Unmarshal([]byte(feed), &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parsed.Items) != 1 {
+		t.Fatalf("Expected one item, got %s", feed)
+	}
+	item := parsed.Items[0]
+	if item.ID != "tag:127.0.0.1,2019-08-20:/go1" || strings.Join(item.Tags, ",") != "golang,Germany" {
+		t.Errorf("Unexpected item %+v", item)
+	}
+	if c := item.Conference; c.StartDate != "2019-08-20" || c.EndDate != "2019-08-21" || c.Location.City != "Berlin" || c.CFPEndDate != "2019-06-01" || c.Twitter != "https://twitter.com/goone" {
+		t.Errorf("Unexpected conference extension %+v", c)
+	}
+}
+
+func TestFeedRejectsUnknownFormat(t *testing.T) {
+	_, err := generateFeed(context.Background(), "golang", feedConferences, []confs.FeedEntry{}, "yaml")
+	if err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestNewFeedEnclosureGuessesImageType(t *testing.T) {
+	og := opengraph.New("https://go1.com/")
+	og.Image = []*opengraph.Image{&opengraph.Image{URL: "https://go1.com/logo.png?v=2"}}
+
+	enclosure := newFeedEnclosure(og)
+	if enclosure == nil || enclosure.Type != "image/png" || enclosure.Length != "0" {
+		t.Errorf("Unexpected enclosure %+v", enclosure)
+	}
+
+	og.Image[0].URL = "https://go1.com/logo"
+	if enclosure := newFeedEnclosure(og); enclosure != nil {
+		t.Errorf("Expected no enclosure for unknown type, got %+v", enclosure)
+	}
+}
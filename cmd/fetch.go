@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// fetchPolicy restricts the requests to the urls taken from the community
+// edited conference data, so they cannot reach internal services or exhaust
+// memory.
+type fetchPolicy struct {
+	// AllowPrivate allows loopback, private and link-local addresses
+	AllowPrivate bool
+	MaxRedirects int
+	// MaxBodySize is the number of bytes read, the rest of the body is cut
+	MaxBodySize int64
+	UserAgent   string
+}
+
+var fetching = fetchPolicy{
+	MaxRedirects: 5,
+	MaxBodySize:  2 << 20,
+	UserAgent:    "confs.tech.push (+https://github.com/flix-tech/confs.tech.push)",
+}
+
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	// NAT64 and 6to4 embed IPv4 addresses, including private ones
+	"64:ff9b::/96",
+	"2002::/16",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+func isBlockedIP(ip net.IP) bool {
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// client returns a client enforcing the policy with the context, the address
+// is checked when connecting so DNS answers cannot bypass it. Proxies from the
+// environment are not used as they would resolve the hosts themselves.
+func (p fetchPolicy) client(ctx context.Context) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); !p.AllowPrivate && (ip == nil || isBlockedIP(ip)) {
+				return fmt.Errorf("Refusing to connect to private address %s", host)
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Transport: fetchTransport{
+			policy: p,
+			ctx:    ctx,
+			base: &http.Transport{
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 30 * time.Second,
+				// Every fetch gets its own client, idle connections would pile up
+				DisableKeepAlives: true,
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > p.MaxRedirects {
+				return fmt.Errorf("Stopped after %d redirects", p.MaxRedirects)
+			}
+
+			return nil
+		},
+	}
+}
+
+// fetchTransport sends every request, including redirects, with the context
+// and the User-Agent, and cuts the response bodies.
+type fetchTransport struct {
+	policy fetchPolicy
+	ctx    context.Context
+	base   http.RoundTripper
+}
+
+func (t fetchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("Refusing to fetch %s url", req.URL.Scheme)
+	}
+
+	req = req.WithContext(t.ctx)
+	if req.Header.Get("User-Agent") == "" {
+		req.Header = cloneHeader(req.Header)
+		req.Header.Set("User-Agent", t.policy.UserAgent)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.policy.MaxBodySize > 0 {
+		resp.Body = limitedBody{Reader: io.LimitReader(resp.Body, t.policy.MaxBodySize), Closer: resp.Body}
+	}
+
+	return resp, nil
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := http.Header{}
+	for name, values := range header {
+		clone[name] = append([]string{}, values...)
+	}
+
+	return clone
+}
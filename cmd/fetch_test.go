@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchPolicyBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	policy := fetching
+	policy.AllowPrivate = false
+
+	_, err := policy.client(context.Background()).Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Errorf("Expected localhost to be blocked, got %v", err)
+	}
+
+	for _, ip := range []string{"10.1.2.3", "172.20.0.1", "192.168.1.1", "169.254.169.254", "::1", "fd00::1", "::ffff:127.0.0.1", "64:ff9b::a9fe:a9fe", "2002:c0a8:101::1"} {
+		if !isBlockedIP(net.ParseIP(ip)) {
+			t.Errorf("Expected %s to be blocked", ip)
+		}
+	}
+	if isBlockedIP(net.ParseIP("93.184.216.34")) {
+		t.Errorf("Expected public address not to be blocked")
+	}
+}
+
+func TestFetchPolicyLimitsRedirectsSchemesAndBodies(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, server.URL+"/loop", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(r.Header.Get("User-Agent") + strings.Repeat(".", 4096)))
+		}
+	}))
+	defer server.Close()
+
+	policy := fetching
+	policy.MaxBodySize = 1024
+	client := policy.client(context.Background())
+
+	_, err := client.Get(server.URL + "/loop")
+	if err == nil || !strings.Contains(err.Error(), "redirects") {
+		t.Errorf("Expected redirect loop to stop, got %v", err)
+	}
+
+	_, err = client.Get(server.URL + "/file")
+	if err == nil || !strings.Contains(err.Error(), "file url") {
+		t.Errorf("Expected file url to be refused, got %v", err)
+	}
+
+	resp, err := client.Get(server.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if len(body) != 1024 || !strings.HasPrefix(string(body), fetching.UserAgent) {
+		t.Errorf("Expected cut body starting with the User-Agent, got %d bytes %.80s", len(body), body)
+	}
+}
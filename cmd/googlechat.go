@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func GooglechatCommand() cli.Command {
+	return cli.Command{
+		Name:   "googlechat",
+		Usage:  "push to google chat",
+		Action: wrapAction(googlechatAction),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "googlechat-url",
+				Usage:  "Google Chat Incoming Webhook url",
+				EnvVar: "GOOGLECHAT_URL",
+			},
+			stateFileFlag,
+			orderFlag,
+			maxPerRunFlag,
+			keepGoingFlag,
+			reportFlag,
+			maxFailuresFlag,
+			opsWebhookURLFlag,
+		},
+	}
+}
+
+func googlechatAction(topic string, conferences []confs.Conference, c *cli.Context) error {
+	webhookURL := c.String("googlechat-url")
+	if webhookURL == "" {
+		return fmt.Errorf("Please provide Google Chat Incoming Webhook url")
+	}
+
+	return pushNewConferences(c, conferences, func(conference enrichedConference) error {
+		return pushToGooglechat(conference.Conference, conference.Opengraph, webhookURL)
+	})
+}
+
+type googlechatOpenLink struct {
+	URL string `json:"url"`
+}
+
+type googlechatOnClick struct {
+	OpenLink googlechatOpenLink `json:"openLink"`
+}
+
+type googlechatButton struct {
+	Text    string            `json:"text"`
+	OnClick googlechatOnClick `json:"onClick"`
+}
+
+type googlechatButtonList struct {
+	Buttons []googlechatButton `json:"buttons"`
+}
+
+type googlechatDecoratedText struct {
+	TopLabel string `json:"topLabel,omitempty"`
+	Text     string `json:"text"`
+}
+
+type googlechatImage struct {
+	ImageURL string `json:"imageUrl"`
+	AltText  string `json:"altText,omitempty"`
+}
+
+type googlechatWidget struct {
+	DecoratedText *googlechatDecoratedText `json:"decoratedText,omitempty"`
+	Image         *googlechatImage         `json:"image,omitempty"`
+	ButtonList    *googlechatButtonList    `json:"buttonList,omitempty"`
+}
+
+type googlechatSection struct {
+	Widgets []googlechatWidget `json:"widgets"`
+}
+
+type googlechatCardHeader struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+}
+
+type googlechatCard struct {
+	Header   googlechatCardHeader `json:"header"`
+	Sections []googlechatSection  `json:"sections"`
+}
+
+type googlechatCardWithID struct {
+	CardID string         `json:"cardId"`
+	Card   googlechatCard `json:"card"`
+}
+
+type googlechatMessage struct {
+	Text    string                 `json:"text,omitempty"`
+	CardsV2 []googlechatCardWithID `json:"cardsV2"`
+}
+
+func newGooglechatMessage(c confs.Conference, og *opengraph.OpenGraph) googlechatMessage {
+	widgets := []googlechatWidget{
+		googlechatWidget{DecoratedText: &googlechatDecoratedText{TopLabel: "Location", Text: formatLocation(c)}},
+		googlechatWidget{DecoratedText: &googlechatDecoratedText{TopLabel: "Dates", Text: formatDateRange(c)}},
+	}
+	if og.Description != "" {
+		widgets = append(widgets, googlechatWidget{DecoratedText: &googlechatDecoratedText{Text: og.Description}})
+	}
+	if len(og.Image) > 0 {
+		widgets = append(widgets, googlechatWidget{Image: &googlechatImage{ImageURL: og.Image[0].URL, AltText: c.Name}})
+	}
+
+	buttons := []googlechatButton{
+		googlechatButton{Text: "Website", OnClick: googlechatOnClick{OpenLink: googlechatOpenLink{URL: c.URL}}},
+	}
+	if c.CFPUrl != "" {
+		buttons = append(buttons, googlechatButton{Text: "Submit talk (CFP)", OnClick: googlechatOnClick{OpenLink: googlechatOpenLink{URL: c.CFPUrl}}})
+	}
+	widgets = append(widgets, googlechatWidget{ButtonList: &googlechatButtonList{Buttons: buttons}})
+
+	return googlechatMessage{
+		CardsV2: []googlechatCardWithID{
+			googlechatCardWithID{
+				CardID: c.URL,
+				Card: googlechatCard{
+					Header:   googlechatCardHeader{Title: c.Name, Subtitle: c.URL},
+					Sections: []googlechatSection{googlechatSection{Widgets: widgets}},
+				},
+			},
+		},
+	}
+}
+
+func pushToGooglechat(c confs.Conference, og *opengraph.OpenGraph, webhookURL string) error {
+	return postJSON(webhookURL, newGooglechatMessage(c, og), "google chat")
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/otiai10/opengraph"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestGooglechatMessageHasCFPButton(t *testing.T) {
+	message := newGooglechatMessage(confs.Conference{
+		Name:      "Go one",
+		URL:       "https://go1.com/",
+		StartDate: "2019-08-20",
+		EndDate:   "2019-08-20",
+		City:      "Berlin",
+		Country:   "Germany",
+		CFPUrl:    "https://go1.com/cfp",
+	}, opengraph.New("https://go1.com/"))
+
+	widgets := message.CardsV2[0].Card.Sections[0].Widgets
+	buttons := widgets[len(widgets)-1].ButtonList.Buttons
+	if len(buttons) != 2 || buttons[1].OnClick.OpenLink.URL != "https://go1.com/cfp" {
+		t.Errorf("Expected Website and CFP buttons, got %+v", buttons)
+	}
+}
+
+func TestPushToGooglechat(t *testing.T) {
+	var received googlechatMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	err := pushToGooglechat(confs.Conference{
+		Name:      "Go two",
+		URL:       "https://go2.com/",
+		StartDate: "2019-08-21",
+		EndDate:   "2019-08-21",
+		City:      "Mariupol",
+		Country:   "Ukraine",
+	}, opengraph.New("https://go2.com/"), server.URL)
+
+	if err != nil {
+		t.Errorf("Got error when pushing to google chat: %s", err)
+	}
+	if received.CardsV2[0].Card.Header.Title != "Go two" {
+		t.Errorf("Expected card title 'Go two', got '%s'", received.CardsV2[0].Card.Header.Title)
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func IcalCommand() cli.Command {
+	return cli.Command{
+		Name:   "ical",
+		Usage:  "generate iCalendar (.ics) calendar",
+		Action: wrapOutputAction(icalAction, func(c *cli.Context) string { return ".ics" }),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "cfp",
+				Usage: "Add an event for every open CFP deadline",
+			},
+			cli.IntFlag{
+				Name:  "cfp-alarm-days",
+				Usage: "Remind this many days before a CFP deadline, 0 means no reminder",
+			},
+			outputFlag,
+		},
+	}
+}
+
+func icalAction(topic string, conferences []confs.Conference, c *cli.Context) (string, error) {
+	return generateICalendar(topic, conferences, c.Bool("cfp"), c.Int("cfp-alarm-days"), time.Now()), nil
+}
+
+// icalWriter writes content lines, escaped and folded as RFC 5545 requires.
+type icalWriter struct {
+	strings.Builder
+}
+
+func (w *icalWriter) line(name string, value string) {
+	line := name + ":" + value
+	for len(line) > 75 {
+		// Fold at 75 octets without splitting a UTF-8 sequence
+		i := 75
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		w.WriteString(line[:i] + "\r\n")
+		line = " " + line[i:]
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func (w *icalWriter) text(name string, value string) {
+	w.line(name, strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value))
+}
+
+// generateICalendar renders every conference as an all-day event, with cfp
+// open CFP deadlines get their own event with an optional alarm.
+func generateICalendar(topic string, conferences []confs.Conference, cfp bool, alarmDays int, now time.Time) string {
+	w := &icalWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//confs.tech.push//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.text("X-WR-CALNAME", topic+" tech conferences")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	today := now.Format("2006-01-02")
+	for _, c := range conferences {
+		start, err := time.Parse("2006-01-02", c.StartDate)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse("2006-01-02", c.EndDate)
+		if err != nil || end.Before(start) {
+			end = start
+		}
+
+		w.line("BEGIN", "VEVENT")
+		w.text("UID", formatTagURI(c))
+		w.line("DTSTAMP", stamp)
+		w.line("DTSTART;VALUE=DATE", start.Format("20060102"))
+		w.line("DTEND;VALUE=DATE", end.AddDate(0, 0, 1).Format("20060102"))
+		w.text("SUMMARY", c.Name)
+		w.text("LOCATION", formatLocation(c))
+		w.line("URL", c.URL)
+		w.line("END", "VEVENT")
+
+		if !cfp || c.CFPEndDate < today {
+			continue
+		}
+		cfpEnd, err := time.Parse("2006-01-02", c.CFPEndDate)
+		if err != nil {
+			continue
+		}
+		cfpURL := c.CFPUrl
+		if cfpURL == "" {
+			cfpURL = c.URL
+		}
+
+		w.line("BEGIN", "VEVENT")
+		w.text("UID", formatTagURI(c)+"#cfp")
+		w.line("DTSTAMP", stamp)
+		w.line("DTSTART;VALUE=DATE", cfpEnd.Format("20060102"))
+		w.line("DTEND;VALUE=DATE", cfpEnd.AddDate(0, 0, 1).Format("20060102"))
+		w.text("SUMMARY", "CFP closes: "+c.Name)
+		w.line("URL", cfpURL)
+		if alarmDays > 0 {
+			w.line("BEGIN", "VALARM")
+			w.line("ACTION", "DISPLAY")
+			w.line("TRIGGER", fmt.Sprintf("-P%dD", alarmDays))
+			w.text("DESCRIPTION", fmt.Sprintf("The CFP of %s closes in %d days", c.Name, alarmDays))
+			w.line("END", "VALARM")
+		}
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+
+	return w.String()
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestGenerateICalendar(t *testing.T) {
+	now := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	conferences := []confs.Conference{
+		confs.Conference{Name: "Go one; the first", URL: "https://go1.com/", StartDate: "2019-08-20", EndDate: "2019-08-21", City: "Berlin", Country: "Germany", CFPUrl: "https://go1.com/cfp", CFPEndDate: "2019-06-01"},
+		confs.Conference{Name: "Go two", URL: "https://go2.com/", StartDate: "2019-08-22", EndDate: "2019-08-22", City: "Mariupol", Country: "Ukraine", CFPEndDate: "2019-04-01"},
+	}
+
+	calendar := generateICalendar("golang", conferences, true, 7, now)
+
+	expected := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//confs.tech.push//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:golang tech conferences",
+		"BEGIN:VEVENT",
+		"UID:tag:go1.com\\,2019-08-20:/",
+		"DTSTAMP:20190501T100000Z",
+		"DTSTART;VALUE=DATE:20190820",
+		"DTEND;VALUE=DATE:20190822",
+		"SUMMARY:Go one\\; the first",
+		"LOCATION:Berlin\\, Germany 🇩🇪",
+		"URL:https://go1.com/",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:tag:go1.com\\,2019-08-20:/#cfp",
+		"DTSTAMP:20190501T100000Z",
+		"DTSTART;VALUE=DATE:20190601",
+		"DTEND;VALUE=DATE:20190602",
+		"SUMMARY:CFP closes: Go one\\; the first",
+		"URL:https://go1.com/cfp",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-P7D",
+		"DESCRIPTION:The CFP of Go one\\; the first closes in 7 days",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:tag:go2.com\\,2019-08-22:/",
+		"DTSTAMP:20190501T100000Z",
+		"DTSTART;VALUE=DATE:20190822",
+		"DTEND;VALUE=DATE:20190823",
+		"SUMMARY:Go two",
+		"LOCATION:Mariupol\\, Ukraine 🇺🇦",
+		"URL:https://go2.com/",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if calendar != expected {
+		t.Errorf("Unexpected calendar:\n%s", calendar)
+	}
+}
+
+func TestICalendarFoldsLongLines(t *testing.T) {
+	w := &icalWriter{}
+	w.text("SUMMARY", strings.Repeat("Gö ", 40))
+
+	for _, line := range strings.Split(strings.TrimSuffix(w.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 || !strings.HasPrefix(line, "SUMMARY:") && !strings.HasPrefix(line, " ") {
+			t.Errorf("Unexpected folded line %q", line)
+		}
+	}
+	if unfolded := strings.Replace(w.String(), "\r\n ", "", -1); unfolded != "SUMMARY:"+strings.Repeat("Gö ", 40)+"\r\n" {
+		t.Errorf("Unexpected unfolded line %q", unfolded)
+	}
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// Registering the decoders of the thumbnail sources
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/otiai10/opengraph"
+)
+
+// imagePolicy decides which preview images are embedded in the messages, the
+// images which are too large are replaced by thumbnails when Dir is set.
+type imagePolicy struct {
+	MaxSize int64
+	// Dir is where the thumbnails are written, it is served at BaseURL
+	Dir     string
+	BaseURL string
+	Width   int
+	// MaxPixels keeps images small in bytes but huge once decoded out of memory
+	MaxPixels int
+	// Candidates is the number of images of a page tried until one is valid
+	Candidates int
+}
+
+var images = imagePolicy{
+	MaxSize:    1 << 20,
+	Width:      600,
+	MaxPixels:  25000000,
+	Candidates: 5,
+}
+
+// validateImages returns the first image of the page which can be embedded,
+// relative urls are resolved against pageURL and http urls are upgraded to
+// https when possible.
+func (p imagePolicy) validateImages(client *http.Client, pageURL string, candidates []*opengraph.Image) []*opengraph.Image {
+	tried := map[string]bool{}
+	for _, candidate := range candidates {
+		if candidate == nil || candidate.URL == "" || len(tried) >= p.Candidates {
+			continue
+		}
+
+		imageURL := resolveURL(pageURL, candidate.URL)
+		if tried[imageURL] {
+			continue
+		}
+		tried[imageURL] = true
+
+		urls := []string{imageURL}
+		if strings.HasPrefix(imageURL, "http://") {
+			urls = []string{"https://" + strings.TrimPrefix(imageURL, "http://"), imageURL}
+		}
+
+		for _, u := range urls {
+			validated, err := p.validateImage(client, u)
+			if err == nil {
+				return []*opengraph.Image{validated}
+			}
+		}
+	}
+
+	return []*opengraph.Image{}
+}
+
+// isMissingThumbnail tells whether the image of the cached opengraph data is a
+// thumbnail whose file was removed since.
+func (p imagePolicy) isMissingThumbnail(og *opengraph.OpenGraph) bool {
+	if p.Dir == "" || og == nil || len(og.Image) == 0 {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(p.BaseURL, "/") + "/"
+	if !strings.HasPrefix(og.Image[0].URL, prefix) {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(p.Dir, filepath.Base(strings.TrimPrefix(og.Image[0].URL, prefix))))
+
+	return err != nil
+}
+
+// validateImage probes the image and returns it or its thumbnail.
+func (p imagePolicy) validateImage(client *http.Client, imageURL string) (*opengraph.Image, error) {
+	resp, err := client.Get(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Got response code %d when fetching image %s", resp.StatusCode, imageURL)
+	}
+
+	imageType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(imageType, "image/") {
+		return nil, fmt.Errorf("Invalid image type %s", imageType)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// A body as large as the fetch limit was cut
+	truncated := fetching.MaxBodySize > 0 && int64(len(content)) >= fetching.MaxBodySize
+	tooLarge := truncated || resp.ContentLength > p.MaxSize || int64(len(content)) > p.MaxSize
+
+	if p.Dir == "" {
+		if tooLarge {
+			return nil, fmt.Errorf("Image %s is larger than %d bytes", imageURL, p.MaxSize)
+		}
+		return &opengraph.Image{URL: imageURL, Type: imageType}, nil
+	}
+
+	if truncated {
+		return nil, fmt.Errorf("Image %s is larger than %d bytes", imageURL, fetching.MaxBodySize)
+	}
+
+	thumbnail, err := p.writeThumbnail(imageURL, content)
+	if err == image.ErrFormat && !tooLarge {
+		return &opengraph.Image{URL: imageURL, Type: imageType}, nil // Formats without decoder are embedded as they are
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return thumbnail, nil
+}
+
+// writeThumbnail scales the image down to the policy width and writes it as
+// JPEG named after the image url.
+func (p imagePolicy) writeThumbnail(imageURL string, content []byte) (*opengraph.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > int64(p.MaxPixels) {
+		return nil, fmt.Errorf("Image %s has too many pixels (%dx%d)", imageURL, config.Width, config.Height)
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	scaled := scaleImage(source, p.Width)
+
+	var encoded bytes.Buffer
+	err = jpeg.Encode(&encoded, scaled, &jpeg.Options{Quality: 85})
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%x.jpg", sha1.Sum([]byte(imageURL)))
+	if !delivery.DryRun {
+		err = os.MkdirAll(p.Dir, 0755)
+		if err == nil {
+			err = replaceFile(filepath.Join(p.Dir, name), encoded.Bytes())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bounds := scaled.Bounds()
+
+	return &opengraph.Image{
+		URL:    strings.TrimSuffix(p.BaseURL, "/") + "/" + name,
+		Type:   "image/jpeg",
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+	}, nil
+}
+
+// scaleImage shrinks the image to the width by averaging the source pixels,
+// smaller images are only copied. Transparent areas become white as JPEG has
+// no alpha channel.
+func scaleImage(source image.Image, width int) *image.RGBA {
+	bounds := source.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		width = bounds.Dx()
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := bounds.Min.Y+y*bounds.Dy()/height, bounds.Min.Y+(y+1)*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			x0, x1 := bounds.Min.X+x*bounds.Dx()/width, bounds.Min.X+(x+1)*bounds.Dx()/width
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1 || sy == y0; sy++ {
+				for sx := x0; sx < x1 || sx == x0; sx++ {
+					cr, cg, cb, ca := source.At(sx, sy).RGBA()
+					// Blending premultiplied colors onto white
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					b += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			scaled.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: 0xffff})
+		}
+	}
+
+	return scaled
+}
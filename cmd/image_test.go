@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otiai10/opengraph"
+)
+
+func encodeTestPNG(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+
+	var encoded bytes.Buffer
+	err := png.Encode(&encoded, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return encoded.Bytes()
+}
+
+func startImageServer(t *testing.T) *httptest.Server {
+	large := encodeTestPNG(t, 400, 200)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/conf/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html></html>"))
+		case "/images/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(large)
+		case "/images/small.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(encodeTestPNG(t, 4, 4))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestValidateImagesSkipsInvalidImages(t *testing.T) {
+	server := startImageServer(t)
+	defer server.Close()
+
+	policy := images
+	policy.MaxSize = 1000
+	policy.Candidates = 3
+
+	validated := policy.validateImages(fetching.client(context.Background()), server.URL+"/conf/index.html", []*opengraph.Image{
+		&opengraph.Image{URL: "missing.png"},
+		&opengraph.Image{URL: "page.html"},
+		&opengraph.Image{URL: "/images/large.png"},
+		&opengraph.Image{URL: "../images/small.png"},
+	})
+	if len(validated) != 0 {
+		t.Errorf("Expected only %d candidates to be tried, got %+v", policy.Candidates, validated[0])
+	}
+
+	policy.Candidates = 4
+	validated = policy.validateImages(fetching.client(context.Background()), server.URL+"/conf/index.html", []*opengraph.Image{
+		&opengraph.Image{URL: "missing.png"},
+		&opengraph.Image{URL: "page.html"},
+		&opengraph.Image{URL: "/images/large.png"},
+		&opengraph.Image{URL: "../images/small.png"},
+	})
+	if len(validated) != 1 || validated[0].URL != server.URL+"/images/small.png" || validated[0].Type != "image/png" {
+		t.Errorf("Expected the small image with resolved url, got %+v", validated)
+	}
+}
+
+func TestValidateImageWritesThumbnail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thumbnails")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := startImageServer(t)
+	defer server.Close()
+
+	policy := images
+	policy.MaxSize = 1000
+	policy.Dir = filepath.Join(dir, "thumbnails")
+	policy.BaseURL = "https://feeds.example/thumbnails/"
+	policy.Width = 100
+
+	thumbnail, err := policy.validateImage(fetching.client(context.Background()), server.URL+"/images/large.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbnail.Type != "image/jpeg" || thumbnail.Width != 100 || thumbnail.Height != 50 {
+		t.Errorf("Expected 100x50 JPEG thumbnail, got %+v", thumbnail)
+	}
+	if !strings.HasPrefix(thumbnail.URL, "https://feeds.example/thumbnails/") {
+		t.Fatalf("Expected thumbnail url under the base url, got %s", thumbnail.URL)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(policy.Dir, strings.TrimPrefix(thumbnail.URL, "https://feeds.example/thumbnails/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(content))
+	if err != nil || config.Width != 100 || config.Height != 50 {
+		t.Errorf("Expected 100x50 JPEG file, got %+v %v", config, err)
+	}
+}
+
+func TestValidateImageRejectsTooManyPixels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thumbnails")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := startImageServer(t)
+	defer server.Close()
+
+	policy := images
+	policy.Dir = dir
+	policy.BaseURL = "https://feeds.example/thumbnails"
+	policy.MaxPixels = 400 * 199
+
+	_, err = policy.validateImage(fetching.client(context.Background()), server.URL+"/images/large.png")
+	if err == nil {
+		t.Errorf("Expected 400x200 image to be rejected")
+	}
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+const mastodonStatusLimit = 500
+
+func MastodonCommand() cli.Command {
+	return cli.Command{
+		Name:   "mastodon",
+		Usage:  "post statuses to mastodon",
+		Action: wrapAction(mastodonAction),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "mastodon-url",
+				Usage:  "Mastodon instance url, e.g. https://mastodon.social",
+				EnvVar: "MASTODON_URL",
+			},
+			cli.StringFlag{
+				Name:   "mastodon-token",
+				Usage:  "Mastodon access token with write:statuses and write:media scopes",
+				EnvVar: "MASTODON_TOKEN",
+			},
+			cli.StringFlag{
+				Name:   "mastodon-visibility",
+				Value:  "public",
+				Usage:  "Status visibility: public, unlisted, private or direct",
+				EnvVar: "MASTODON_VISIBILITY",
+			},
+			cli.StringSliceFlag{
+				Name:   "mastodon-hashtags",
+				Usage:  "Additional hashtags, the topic is always added",
+				EnvVar: "MASTODON_HASHTAGS",
+			},
+			stateFileFlag,
+			orderFlag,
+			maxPerRunFlag,
+			keepGoingFlag,
+			reportFlag,
+			maxFailuresFlag,
+			opsWebhookURLFlag,
+		},
+	}
+}
+
+type mastodonConfig struct {
+	URL        string
+	Token      string
+	Visibility string
+	Hashtags   []string
+}
+
+func mastodonAction(topic string, conferences []confs.Conference, c *cli.Context) error {
+	config := mastodonConfig{
+		URL:        strings.TrimSuffix(c.String("mastodon-url"), "/"),
+		Token:      c.String("mastodon-token"),
+		Visibility: c.String("mastodon-visibility"),
+		Hashtags:   append([]string{topic}, c.StringSlice("mastodon-hashtags")...),
+	}
+	if config.URL == "" || config.Token == "" {
+		return fmt.Errorf("Please provide Mastodon instance url and access token")
+	}
+	switch config.Visibility {
+	case "public", "unlisted", "private", "direct":
+	default:
+		return fmt.Errorf("Invalid Mastodon visibility %s", config.Visibility)
+	}
+
+	return pushNewConferences(c, conferences, func(conference enrichedConference) error {
+		return pushToMastodon(conference.Conference, conference.Opengraph, config)
+	})
+}
+
+type mastodonMedia struct {
+	ID string `json:"id"`
+}
+
+type mastodonStatus struct {
+	Status     string   `json:"status"`
+	Visibility string   `json:"visibility"`
+	MediaIDs   []string `json:"media_ids,omitempty"`
+}
+
+func pushToMastodon(c confs.Conference, og *opengraph.OpenGraph, config mastodonConfig) error {
+	status := mastodonStatus{
+		Status:     formatMastodonStatus(c, og, config.Hashtags),
+		Visibility: config.Visibility,
+	}
+
+	if len(og.Image) > 0 {
+		media, err := uploadMastodonMedia(og.Image[0].URL, c.Name, config)
+		if err == nil && media.ID != "" {
+			status.MediaIDs = []string{media.ID}
+		} // Ignoring the error, the status is still useful without the image. Dry runs get no media id
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+config.Token)
+	header.Set("Idempotency-Key", c.URL+"#"+c.StartDate)
+
+	return sendJSON(http.MethodPost, config.URL+"/api/v1/statuses", header, status, nil, "mastodon")
+}
+
+// formatMastodonStatus renders the status text, the opengraph description is
+// shortened or dropped to stay within the instance character limit.
+func formatMastodonStatus(c confs.Conference, og *opengraph.OpenGraph, hashtags []string) string {
+	status := fmt.Sprintf("%s\n%s\n\n📍 %s\n📅 %s", c.Name, c.URL, formatLocation(c), formatDateRange(c))
+	if twitterURL := formatTwitterURL(c); twitterURL != "" {
+		status += "\n🐦 " + twitterURL
+	}
+
+	tags := []string{}
+	for _, hashtag := range hashtags {
+		hashtag = strings.NewReplacer("#", "", "-", "", " ", "").Replace(hashtag)
+		if hashtag != "" {
+			tags = append(tags, "#"+hashtag)
+		}
+	}
+	footer := ""
+	if len(tags) > 0 {
+		footer = "\n\n" + strings.Join(tags, " ")
+	}
+
+	if og.Description != "" {
+		available := mastodonStatusLimit - utf8.RuneCountInString(status+footer) - len("\n\n")
+		description := []rune(og.Description)
+		if len(description) > available && available > 0 {
+			description = append(description[:available-1], '…')
+		}
+		if len(description) <= available {
+			status += "\n\n" + string(description)
+		}
+	}
+
+	status += footer
+	if runes := []rune(status); len(runes) > mastodonStatusLimit {
+		status = string(runes[:mastodonStatusLimit-1]) + "…"
+	}
+
+	return status
+}
+
+func uploadMastodonMedia(imageURL string, description string, config mastodonConfig) (*mastodonMedia, error) {
+	resp, err := fetching.client(context.Background()).Get(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Got response code %d when downloading %s", resp.StatusCode, imageURL)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	file, err := writer.CreateFormFile("file", path.Base(resp.Request.URL.Path))
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.WriteField("description", description)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, config.URL+"/api/v2/media", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.Token)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	media := &mastodonMedia{}
+	err = sendRequest(req, media, "mastodon")
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
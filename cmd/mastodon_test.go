@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/otiai10/opengraph"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestMastodonStatusFitsLimit(t *testing.T) {
+	og := opengraph.New("https://go1.com/")
+	og.Description = strings.Repeat("Gophers ", 100)
+
+	status := formatMastodonStatus(confs.Conference{
+		Name:      "Go one",
+		URL:       "https://go1.com/",
+		StartDate: "2019-08-20",
+		EndDate:   "2019-08-21",
+		City:      "Berlin",
+		Country:   "Germany",
+		Twitter:   "@goone",
+	}, og, []string{"golang", "#tech-conference"})
+
+	if length := utf8.RuneCountInString(status); length > mastodonStatusLimit {
+		t.Errorf("Status is %d characters long", length)
+	}
+	for _, expected := range []string{"Berlin, Germany 🇩🇪", "2019-08-20 — 2019-08-21", "https://twitter.com/goone", "#golang #techconference"} {
+		if !strings.Contains(status, expected) {
+			t.Errorf("Expected status to contain '%s', got:\n%s", expected, status)
+		}
+	}
+}
+
+func TestPushToMastodonUploadsImage(t *testing.T) {
+	var status mastodonStatus
+	var altText string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			_, _ = w.Write([]byte("png"))
+		case "/api/v2/media":
+			altText = r.FormValue("description")
+			_, _ = w.Write([]byte(`{"id":"42"}`))
+		case "/api/v1/statuses":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&status)
+		}
+	}))
+	defer server.Close()
+
+	og := opengraph.New("https://go1.com/")
+	og.Image = []*opengraph.Image{&opengraph.Image{URL: server.URL + "/image.png"}}
+
+	err := pushToMastodon(confs.Conference{
+		Name:      "Go one",
+		URL:       "https://go1.com/",
+		StartDate: "2019-08-20",
+		EndDate:   "2019-08-20",
+		City:      "Berlin",
+		Country:   "Germany",
+	}, og, mastodonConfig{URL: server.URL, Token: "secret", Visibility: "unlisted", Hashtags: []string{"golang"}})
+
+	if err != nil {
+		t.Fatalf("Got error when posting to mastodon: %s", err)
+	}
+	if altText != "Go one" {
+		t.Errorf("Expected image alt text 'Go one', got '%s'", altText)
+	}
+	if status.Visibility != "unlisted" || len(status.MediaIDs) != 1 || status.MediaIDs[0] != "42" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestPushToMastodonDryRunOmitsMediaIDs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	dryRunOutput = &output
+	delivery.DryRun = true
+	defer func() {
+		dryRunOutput = os.Stdout
+		delivery.DryRun = false
+	}()
+
+	og := opengraph.New("https://go1.com/")
+	og.Image = []*opengraph.Image{&opengraph.Image{URL: server.URL + "/image.png"}}
+
+	err := pushToMastodon(confs.Conference{Name: "Go one", URL: "https://go1.com/"}, og, mastodonConfig{URL: "https://mastodon.example", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output.String(), "media_ids") {
+		t.Errorf("Expected no media ids in dry-run, got:\n%s", output.String())
+	}
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/otiai10/opengraph"
+	"golang.org/x/net/html"
+)
+
+// pageMetadata is the link preview of a conference website, every field is
+// taken from the first source providing it: OpenGraph, Twitter Card, the meta
+// description, a JSON-LD Event and finally the images of the page.
+type pageMetadata struct {
+	Title       string `json:",omitempty"`
+	Description string `json:",omitempty"`
+	Image       string `json:",omitempty"`
+	Venue       string `json:",omitempty"`
+	Price       string `json:",omitempty"`
+}
+
+// minImageWidth is the width from which an <img> is large enough for a preview.
+const minImageWidth = 200
+
+// pageTags are the preview related tags of a page in document order.
+type pageTags struct {
+	meta     map[string]string
+	title    string
+	jsonLD   []string
+	images   []string
+	favicons map[string]string
+}
+
+// extractMetadata parses the page, relative urls are resolved against pageURL.
+// The image candidates are all images of the page in the order of the sources.
+func extractMetadata(page []byte, pageURL string) (pageMetadata, []string) {
+	tags := parsePageTags(page)
+	event := findJSONLDEvent(tags.jsonLD)
+
+	candidates := []string{}
+	seen := map[string]bool{}
+	addCandidates := func(urls ...string) {
+		for _, u := range urls {
+			if u = resolveURL(pageURL, u); u != "" && !seen[u] {
+				seen[u] = true
+				candidates = append(candidates, u)
+			}
+		}
+	}
+	addCandidates(tags.meta["og:image"], tags.meta["og:image:url"], tags.meta["twitter:image"], tags.meta["twitter:image:src"])
+	addCandidates(jsonLDImages(event.Image)...)
+	addCandidates(tags.images...)
+	addCandidates(tags.favicons["apple-touch-icon"], tags.favicons["icon"], tags.favicons["shortcut icon"])
+
+	metadata := pageMetadata{
+		Title:       firstNonEmpty(tags.meta["og:title"], tags.meta["twitter:title"], event.Name, tags.title),
+		Description: firstNonEmpty(tags.meta["og:description"], tags.meta["twitter:description"], tags.meta["description"], event.Description),
+		Venue:       jsonLDVenue(event.Location),
+		Price:       jsonLDPrice(event.Offers),
+	}
+	if len(candidates) > 0 {
+		metadata.Image = candidates[0]
+	}
+
+	return metadata, candidates
+}
+
+// completeOpengraph fills the texts the page has no OpenGraph tags for, the
+// images are completed from the candidates when they are validated.
+func completeOpengraph(og *opengraph.OpenGraph, metadata pageMetadata) {
+	if og.Title == "" {
+		og.Title = metadata.Title
+	}
+	if og.Description == "" {
+		og.Description = metadata.Description
+	}
+}
+
+func parsePageTags(page []byte) pageTags {
+	tags := pageTags{meta: map[string]string{}, favicons: map[string]string{}}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return tags
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		attrs := map[string]string{}
+		for _, attr := range token.Attr {
+			attrs[strings.ToLower(attr.Key)] = strings.TrimSpace(attr.Val)
+		}
+
+		switch token.Data {
+		case "meta":
+			name := strings.ToLower(firstNonEmpty(attrs["property"], attrs["name"]))
+			if _, found := tags.meta[name]; !found && name != "" && attrs["content"] != "" {
+				tags.meta[name] = attrs["content"]
+			}
+		case "title":
+			if tags.title == "" && tokenizer.Next() == html.TextToken {
+				tags.title = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case "script":
+			if strings.ToLower(attrs["type"]) == "application/ld+json" && tokenizer.Next() == html.TextToken {
+				tags.jsonLD = append(tags.jsonLD, string(tokenizer.Text()))
+			}
+		case "img":
+			width, _ := strconv.Atoi(strings.TrimSuffix(attrs["width"], "px"))
+			if width >= minImageWidth && attrs["src"] != "" {
+				tags.images = append(tags.images, attrs["src"])
+			}
+		case "link":
+			rel := strings.ToLower(attrs["rel"])
+			if _, found := tags.favicons[rel]; !found && attrs["href"] != "" {
+				tags.favicons[rel] = attrs["href"]
+			}
+		}
+	}
+}
+
+// jsonLDEvent is the part of a schema.org Event used for previews, the fields
+// which may be a text, an object or a list are decoded later.
+type jsonLDEvent struct {
+	Type        interface{}     `json:"@type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Image       json.RawMessage `json:"image"`
+	Location    json.RawMessage `json:"location"`
+	Offers      json.RawMessage `json:"offers"`
+	Graph       []jsonLDEvent   `json:"@graph"`
+}
+
+func (e jsonLDEvent) isEvent() bool {
+	types := []interface{}{e.Type}
+	if list, ok := e.Type.([]interface{}); ok {
+		types = list
+	}
+	for _, t := range types {
+		if name, ok := t.(string); ok && strings.HasSuffix(name, "Event") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findJSONLDEvent(scripts []string) jsonLDEvent {
+	for _, script := range scripts {
+		candidates := []jsonLDEvent{}
+		if json.Unmarshal([]byte(script), &candidates) != nil {
+			var single jsonLDEvent
+			if json.Unmarshal([]byte(script), &single) != nil {
+				continue
+			}
+			candidates = append([]jsonLDEvent{single}, single.Graph...)
+		}
+
+		for _, candidate := range candidates {
+			if candidate.isEvent() {
+				return candidate
+			}
+		}
+	}
+
+	return jsonLDEvent{}
+}
+
+// jsonLDImages returns the urls of images given as url, ImageObject or a list
+// of them.
+func jsonLDImages(raw json.RawMessage) []string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []string{text}
+	}
+
+	var object struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(raw, &object) == nil && object.URL != "" {
+		return []string{object.URL}
+	}
+
+	urls := []string{}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		for _, item := range list {
+			urls = append(urls, jsonLDImages(item)...)
+		}
+	}
+
+	return urls
+}
+
+// jsonLDVenue returns the venue of a location given as text, Place or a list
+// of them.
+func jsonLDVenue(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return strings.TrimSpace(text)
+	}
+
+	var location struct {
+		Name    string          `json:"name"`
+		Address json.RawMessage `json:"address"`
+	}
+	if json.Unmarshal(raw, &location) != nil {
+		var list []json.RawMessage
+		if json.Unmarshal(raw, &list) == nil && len(list) > 0 {
+			return jsonLDVenue(list[0])
+		}
+		return ""
+	}
+
+	var address string
+	if json.Unmarshal(location.Address, &address) != nil {
+		var postal struct {
+			StreetAddress   string `json:"streetAddress"`
+			AddressLocality string `json:"addressLocality"`
+		}
+		_ = json.Unmarshal(location.Address, &postal)
+		parts := []string{}
+		for _, part := range []string{postal.StreetAddress, postal.AddressLocality} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		address = strings.Join(parts, ", ")
+	}
+
+	if location.Name != "" && address != "" {
+		return location.Name + ", " + address
+	}
+
+	return firstNonEmpty(location.Name, address)
+}
+
+// jsonLDPrice returns the lowest price of the offers with its currency.
+func jsonLDPrice(raw json.RawMessage) string {
+	type offer struct {
+		Price         interface{} `json:"price"`
+		LowPrice      interface{} `json:"lowPrice"`
+		PriceCurrency string      `json:"priceCurrency"`
+	}
+
+	offers := []offer{}
+	if json.Unmarshal(raw, &offers) != nil {
+		var single offer
+		if json.Unmarshal(raw, &single) != nil {
+			return ""
+		}
+		offers = []offer{single}
+	}
+
+	lowest, price := -1.0, ""
+	for _, o := range offers {
+		value := firstNonEmpty(fmt.Sprint(valueOrEmpty(o.LowPrice)), fmt.Sprint(valueOrEmpty(o.Price)))
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		if lowest < 0 || amount < lowest {
+			lowest = amount
+			price = strings.TrimSpace(value + " " + o.PriceCurrency)
+		}
+	}
+
+	if lowest == 0 {
+		return "Free"
+	}
+
+	return price
+}
+
+func valueOrEmpty(value interface{}) interface{} {
+	if value == nil {
+		return ""
+	}
+
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func resolveURL(base string, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return baseURL.ResolveReference(refURL).String()
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	tests := map[string]pageMetadata{
+		"opengraph": pageMetadata{
+			Title:       "GopherCon EU 2026",
+			Description: "The European Go conference",
+			Image:       "https://gophercon.eu/og.png",
+		},
+		"twitter": pageMetadata{
+			Title:       "RustFest Zürich",
+			Description: "Two days of Rust talks and workshops",
+			Image:       "https://conf.example/images/card.jpg",
+		},
+		"description": pageMetadata{
+			Title:       "DevOpsDays Berlin",
+			Description: "Talks and open spaces about DevOps culture",
+			Image:       "https://conf.example/apple-touch-icon.png",
+		},
+		"jsonld": pageMetadata{
+			Title:       "JSConf EU 2026",
+			Description: "The JavaScript community conference",
+			Image:       "https://jsconf.eu/hero.jpg",
+			Venue:       "Arena Berlin, Eichenstraße 4, Berlin",
+			Price:       "249 EUR",
+		},
+		"images": pageMetadata{
+			Title: "PyCon",
+			Image: "https://conf.example/images/venue.jpg",
+		},
+		"empty": pageMetadata{},
+	}
+
+	for name, expected := range tests {
+		page, err := ioutil.ReadFile(filepath.Join("testdata", "metadata_"+name+".html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		metadata, _ := extractMetadata(page, "https://conf.example/2026/index.html")
+		if metadata != expected {
+			t.Errorf("Expected %s metadata %+v, got %+v", name, expected, metadata)
+		}
+	}
+}
+
+func TestExtractMetadataImageCandidates(t *testing.T) {
+	page, err := ioutil.ReadFile(filepath.Join("testdata", "metadata_images.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, candidates := extractMetadata(page, "https://conf.example/2026/index.html")
+	expected := []string{
+		"https://conf.example/images/venue.jpg",
+		"https://conf.example/images/sponsors.png",
+		"https://conf.example/favicon.ico",
+	}
+	if strings.Join(candidates, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected image candidates %v, got %v", expected, candidates)
+	}
+}
+
+func TestFetchOpengraphCompletesMissingTags(t *testing.T) {
+	page, err := ioutil.ReadFile(filepath.Join("testdata", "metadata_jsonld.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hero.png" {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(encodeTestPNG(t, 4, 4))
+			return
+		}
+		_, _ = w.Write(bytes.Replace(page, []byte("https://jsconf.eu/hero.jpg"), []byte(server.URL+"/hero.png"), 1))
+	}))
+	defer server.Close()
+
+	og, metadata := fetchOpengraph(context.Background(), server.URL)
+	if og.Description != "The JavaScript community conference" {
+		t.Errorf("Expected description from JSON-LD, got %q", og.Description)
+	}
+	if len(og.Image) != 1 || og.Image[0].URL != server.URL+"/hero.png" {
+		t.Errorf("Expected image from JSON-LD, got %+v", og.Image)
+	}
+	if metadata.Venue != "Arena Berlin, Eichenstraße 4, Berlin" {
+		t.Errorf("Expected venue from JSON-LD, got %q", metadata.Venue)
+	}
+}
+
+func TestFetchOpengraphFailsOnErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, _, err := fetchPage(fetching.client(context.Background()), server.URL)
+	if err == nil {
+		t.Errorf("Expected not found page to fail")
+	}
+}
+
+func TestFetchOpengraphFallsBackToNextImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<html><head><meta property="og:image" content="/missing.png"></head>` +
+				`<body><img src="/venue.png" width="800"></body></html>`))
+		case "/venue.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(encodeTestPNG(t, 4, 4))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	og, metadata := fetchOpengraph(context.Background(), server.URL+"/")
+	if len(og.Image) != 1 || og.Image[0].URL != server.URL+"/venue.png" || metadata.Image != og.Image[0].URL {
+		t.Errorf("Expected the <img> after the missing og:image, got %+v", og.Image)
+	}
+}
+
+func TestJSONLDVenueAcceptsText(t *testing.T) {
+	for raw, expected := range map[string]string{
+		`"Online"`: "Online",
+		`[{"@type": "VirtualLocation", "name": "Zoom"}]`:                              "Zoom",
+		`{"@type": "Place", "address": "Alexanderplatz 1, Berlin"}`:                   "Alexanderplatz 1, Berlin",
+		`{"@type": "Place", "name": "bcc", "address": {"addressLocality": "Berlin"}}`: "bcc, Berlin",
+	} {
+		if venue := jsonLDVenue(json.RawMessage(raw)); venue != expected {
+			t.Errorf("Expected venue %q for %s, got %q", expected, raw, venue)
+		}
+	}
+}
+
+func TestFetchPageRejectsOtherContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte(`<html><head><meta name="description" content="Not a page"></head></html>`))
+	}))
+	defer server.Close()
+
+	_, _, err := fetchPage(fetching.client(context.Background()), server.URL)
+	if err == nil {
+		t.Errorf("Expected PDF not to be parsed as HTML")
+	}
+}
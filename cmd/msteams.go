@@ -1,15 +1,13 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"strings"
+	"text/template"
 
 	"gopkg.in/urfave/cli.v1"
 
 	"github.com/flix-tech/confs.tech.push/confs"
-	"github.com/otiai10/opengraph"
 )
 
 func MsteamsCommand() cli.Command {
@@ -17,79 +15,182 @@ func MsteamsCommand() cli.Command {
 		Name:   "msteams",
 		Usage:  "push to msteams",
 		Action: wrapAction(msteamsAction),
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:   "msteams-url",
-				Usage:  "Teams Incoming Webhook url",
+				Usage:  "Teams Incoming Webhook or Workflows webhook url",
 				EnvVar: "MSTEAMS_URL",
 			},
 			cli.StringFlag{
-				Name:  "state-file, s",
-				Value: "state.json",
-				Usage: "State file path",
+				Name:   "msteams-format",
+				Value:  "legacy",
+				Usage:  "Built-in message layout: legacy text for Office 365 connectors, or adaptive cards for Workflows webhooks",
+				EnvVar: "MSTEAMS_FORMAT",
 			},
-		},
+			templateFlag,
+			stateFileFlag,
+			orderFlag,
+			maxPerRunFlag,
+			keepGoingFlag,
+			reportFlag,
+			maxFailuresFlag,
+			opsWebhookURLFlag,
+		}, digestFlags...),
 	}
 }
 
-func msteamsAction(topic string, conferences []confs.Conference, c *cli.Context) error {
-	stateFile := c.String("state-file")
-	processedConferences := confs.LoadState(stateFile)
-
-	conferences = confs.FilterConferences(conferences,
-		confs.NewTestConferenceIsNotOneOf(processedConferences),
-	)
+const msteamsTemplate = `
+{{- $text := printf "**%s**  \n[%s](%s)\n\n%s・%s" .Conference.Name .Conference.URL .Conference.URL (formatLocation .Conference) (formatDateRange .Conference) -}}
+{{- with .Opengraph.Description }}{{ $text = printf "%s\n\n%s" $text . }}{{ end -}}
+{{- with .Opengraph.Image }}{{ $text = printf "%s\n\n![img](%s)" $text (index . 0).URL }}{{ end -}}
+{"text": {{ json $text }}}
+`
+
+const msteamsAdaptiveTemplate = `{
+  "type": "message",
+  "attachments": [
+    {
+      "contentType": "application/vnd.microsoft.card.adaptive",
+      "content": {
+        "$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+        "type": "AdaptiveCard",
+        "version": "1.4",
+        "body": [
+          {"type": "TextBlock", "text": {{ json .Conference.Name }}, "size": "Large", "weight": "Bolder", "wrap": true},
+          {
+            "type": "FactSet",
+            "facts": [
+              {"title": "Location", "value": {{ formatLocation .Conference | json }}},
+              {"title": "Dates", "value": {{ formatDateRange .Conference | json }}}
+            ]
+          }
+          {{- with .Opengraph.Description }},
+          {"type": "TextBlock", "text": {{ json . }}, "wrap": true}
+          {{- end }}
+          {{- with .Opengraph.Image }},
+          {"type": "Image", "url": {{ json (index . 0).URL }}, "altText": {{ json $.Conference.Name }}, "size": "Stretch"}
+          {{- end }}
+        ],
+        "actions": [
+          {"type": "Action.OpenUrl", "title": "Website", "url": {{ json .Conference.URL }}}
+          {{- with .Conference.CFPUrl }},
+          {"type": "Action.OpenUrl", "title": "Submit talk (CFP)", "url": {{ json . }}}
+          {{- end }}
+        ]
+      }
+    }
+  ]
+}
+`
 
-	// Push to slack
+func msteamsAction(topic string, conferences []confs.Conference, c *cli.Context) error {
 	webhookURL := c.String("msteams-url")
 	if webhookURL == "" {
 		return fmt.Errorf("Please provide Teams Incoming Webhook url")
 	}
 
-	for _, c := range conferences {
-		og, err := opengraph.Fetch(c.URL)
-		if err != nil {
-			og = opengraph.New(c.URL) // Ignoring the error, opengraph data is not critical
-		}
+	defaultTemplate, render, err := msteamsLayout(c.String("msteams-format"))
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := loadTemplateOrDefault(c.String("template"), "msteams", defaultTemplate)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("digest") {
+		return pushDigest(c, topic, conferences, msteamsDigestLimit, render, func(payload interface{}) error {
+			return postJSON(webhookURL, payload, "msteams")
+		})
+	}
+
+	return pushNewConferences(c, conferences, func(conference enrichedConference) error {
+		return pushToMsteams(templateData{
+			Topic:      topic,
+			Conference: conference.Conference,
+			Opengraph:  conference.Opengraph,
+			Metadata:   conference.Metadata,
+		}, tmpl, webhookURL)
+	})
+}
+
+// msteamsLayout returns the default template and the digest renderer of the
+// --msteams-format.
+func msteamsLayout(format string) (string, digestRenderer, error) {
+	switch format {
+	case "legacy":
+		return msteamsTemplate, newMsteamsDigestMessage, nil
+	case "adaptive":
+		return msteamsAdaptiveTemplate, newMsteamsAdaptiveDigestMessage, nil
+	}
 
-		err = pushToMsteams(c, og, webhookURL)
-		if err != nil {
-			_ = confs.SaveState(stateFile, processedConferences)
-			return err
-		}
+	return "", nil, fmt.Errorf("Invalid msteams format %s", format)
+}
 
-		processedConferences = append(processedConferences, c)
+func pushToMsteams(data templateData, tmpl *template.Template, webhookURL string) error {
+	body, err := renderJSONTemplate(tmpl, data)
+	if err != nil {
+		return err
 	}
 
-	return confs.SaveState(stateFile, processedConferences)
+	return postJSON(webhookURL, body, "msteams")
 }
 
+// msteamsDigestLimit stays below the 28KB Teams allows for a message.
+const msteamsDigestLimit = 25000
+
 type msteamsMessage struct {
 	Text string `json:"text"`
 }
 
-func pushToMsteams(c confs.Conference, og *opengraph.OpenGraph, webhookURL string) error {
-	text := fmt.Sprintf("**%s**  \n[%s](%s)\n\n%s・%s", c.Name, c.URL, c.URL, formatLocation(c), formatDateRange(c))
-	if og.Description != "" {
-		text += "\n\n" + og.Description
-	}
-	if len(og.Image) > 0 {
-		text += fmt.Sprintf("\n\n![img](%s)", og.Image[0].URL)
+func formatMsteamsDigestGroup(group conferenceGroup) string {
+	lines := []string{}
+	for _, c := range group.Conferences {
+		lines = append(lines, fmt.Sprintf("- [%s](%s) — %s・%s", c.Name, c.URL, formatLocation(c), formatDateRange(c)))
 	}
 
-	message := msteamsMessage{Text: text}
-	messageString, err := json.Marshal(message)
-	if err != nil {
-		return err
+	return strings.Join(lines, "\n")
+}
+
+func newMsteamsDigestMessage(title string, groups []conferenceGroup) (interface{}, error) {
+	text := fmt.Sprintf("**%s**", title)
+	for _, group := range groups {
+		text += fmt.Sprintf("\n\n**%s**\n\n%s", group.Title, formatMsteamsDigestGroup(group))
 	}
 
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(messageString))
-	if err != nil {
-		return err
+	return msteamsMessage{Text: text}, nil
+}
+
+func newMsteamsAdaptiveDigestMessage(title string, groups []conferenceGroup) (interface{}, error) {
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": title, "size": "Large", "weight": "Bolder", "wrap": true},
 	}
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("Got response code %d when sending message to msteams", resp.StatusCode)
+	for _, group := range groups {
+		body = append(body,
+			map[string]interface{}{"type": "TextBlock", "text": group.Title, "weight": "Bolder", "wrap": true},
+			map[string]interface{}{"type": "TextBlock", "text": formatMsteamsDigestGroup(group), "wrap": true},
+		)
 	}
 
-	return nil
+	return newMsteamsAdaptiveMessage(body), nil
+}
+
+// newMsteamsAdaptiveMessage wraps the card body in the message accepted by
+// Workflows webhooks.
+func newMsteamsAdaptiveMessage(body []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			},
+		},
+	}
 }
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// opengraphCacheEntry is a fetched page, failures are cached as well so broken
+// sites are not requested on every run.
+type opengraphCacheEntry struct {
+	Opengraph *opengraph.OpenGraph `json:",omitempty"`
+	// Metadata are the fallbacks for the missing opengraph tags
+	Metadata pageMetadata
+	Failed   bool `json:",omitempty"`
+	Fetched  time.Time
+}
+
+// opengraphCache keeps the opengraph data by url in a file, a nil cache
+// caches nothing.
+type opengraphCache struct {
+	filename    string
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]opengraphCacheEntry
+	changed bool
+}
+
+var ogCache *opengraphCache
+
+func openOpengraphCache(filename string, ttl time.Duration, negativeTTL time.Duration) *opengraphCache {
+	if filename == "" {
+		return nil
+	}
+
+	cache := &opengraphCache{
+		filename:    filename,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     map[string]opengraphCacheEntry{},
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err == nil {
+		err = json.Unmarshal(content, &cache.entries)
+		if err != nil {
+			log.Printf("Ignoring invalid opengraph cache %s: %s", filename, err)
+			cache.entries = map[string]opengraphCacheEntry{}
+		}
+	}
+
+	return cache
+}
+
+func (cache *opengraphCache) isFresh(entry opengraphCacheEntry) bool {
+	ttl := cache.ttl
+	if entry.Failed {
+		ttl = cache.negativeTTL
+	}
+
+	return time.Since(entry.Fetched) < ttl
+}
+
+// get returns the fresh cache entry of the url, the entry is failed if fetching
+// it failed recently.
+func (cache *opengraphCache) get(url string) (opengraphCacheEntry, bool) {
+	if cache == nil {
+		return opengraphCacheEntry{}, false
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, found := cache.entries[url]
+	if !found || !cache.isFresh(entry) || !entry.Failed && entry.Opengraph == nil {
+		return opengraphCacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (cache *opengraphCache) put(url string, og *opengraph.OpenGraph, metadata pageMetadata, err error) {
+	if cache == nil {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry := opengraphCacheEntry{Fetched: time.Now()}
+	if err != nil {
+		entry.Failed = true
+	} else {
+		entry.Opengraph = og
+		entry.Metadata = metadata
+	}
+	cache.entries[url] = entry
+	cache.changed = true
+}
+
+// save writes the cache without the expired entries if anything was fetched.
+// Dry runs do not save it as their thumbnails are not written.
+func (cache *opengraphCache) save() {
+	if cache == nil || delivery.DryRun {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if !cache.changed {
+		return
+	}
+
+	for url, entry := range cache.entries {
+		if !cache.isFresh(entry) {
+			delete(cache.entries, url)
+		}
+	}
+
+	content, err := json.Marshal(cache.entries)
+	if err == nil {
+		err = replaceFile(cache.filename, content)
+	}
+	if err != nil {
+		log.Printf("Could not save opengraph cache %s: %s", cache.filename, err)
+		return
+	}
+
+	cache.changed = false
+}
+
+func CacheCommand() cli.Command {
+	return cli.Command{
+		Name:  "cache",
+		Usage: "manage the opengraph cache",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:      "clear",
+				Usage:     "remove all or the given urls from the opengraph cache",
+				ArgsUsage: "[url...]",
+				Action:    cacheClearAction,
+			},
+		},
+	}
+}
+
+func cacheClearAction(c *cli.Context) error {
+	filename := c.GlobalString("opengraph-cache")
+	if filename == "" {
+		return cli.NewExitError("Please provide the opengraph cache file", 1)
+	}
+
+	if len(c.Args()) == 0 {
+		err := os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return cli.NewExitError(err, 1)
+		}
+
+		fmt.Printf("Opengraph cache %s cleared\n", filename)
+		return nil
+	}
+
+	cache := openOpengraphCache(filename, 0, 0)
+	for _, url := range c.Args() {
+		delete(cache.entries, url)
+	}
+
+	content, err := json.Marshal(cache.entries)
+	if err == nil {
+		err = replaceFile(filename, content)
+	}
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	fmt.Printf("%d urls removed from opengraph cache %s\n", len(c.Args()), filename)
+
+	return nil
+}
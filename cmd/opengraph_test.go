@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otiai10/opengraph"
+)
+
+func TestFetchOpengraphUsesPersistentCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opengraph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`<html><head><meta property="og:description" content="Go conference"></head></html>`))
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(dir, "opengraph.json")
+	ogCache = openOpengraphCache(filename, time.Hour, time.Hour)
+	defer func() { ogCache = nil }()
+
+	fetchOpengraph(context.Background(), server.URL)
+	fetchOpengraph(context.Background(), "http://127.0.0.1:1/down")
+	ogCache.save()
+
+	ogCache = openOpengraphCache(filename, time.Hour, time.Hour)
+	fetchOpengraph(context.Background(), server.URL)
+	if requests != 1 {
+		t.Errorf("Expected cached page not to be fetched again, got %d requests", requests)
+	}
+	if entry, found := ogCache.get("http://127.0.0.1:1/down"); !found || !entry.Failed {
+		t.Errorf("Expected failure to be cached")
+	}
+
+	ogCache = openOpengraphCache(filename, 0, time.Hour)
+	fetchOpengraph(context.Background(), server.URL)
+	if requests != 2 {
+		t.Errorf("Expected expired page to be fetched again, got %d requests", requests)
+	}
+}
+
+func TestFetchOpengraphRefetchesMissingThumbnail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thumbnails")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`<html></html>`))
+	}))
+	defer server.Close()
+
+	images.Dir, images.BaseURL = dir, "https://feeds.example/thumbnails"
+	defer func() { images.Dir, images.BaseURL = "", "" }()
+
+	ogCache = openOpengraphCache(filepath.Join(dir, "opengraph.json"), time.Hour, time.Hour)
+	defer func() { ogCache = nil }()
+
+	og := opengraph.New(server.URL)
+	og.Image = []*opengraph.Image{&opengraph.Image{URL: "https://feeds.example/thumbnails/conference.jpg"}}
+	ogCache.put(server.URL, og, pageMetadata{}, nil)
+
+	fetchOpengraph(context.Background(), server.URL)
+	if requests != 1 {
+		t.Errorf("Expected page with missing thumbnail to be fetched again, got %d requests", requests)
+	}
+
+	ogCache.put(server.URL, og, pageMetadata{}, nil)
+	err = ioutil.WriteFile(filepath.Join(dir, "conference.jpg"), []byte("jpeg"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetchOpengraph(context.Background(), server.URL)
+	if requests != 1 {
+		t.Errorf("Expected page with thumbnail to be cached, got %d requests", requests)
+	}
+}
+
+func TestOpengraphCacheIsNotSavedInDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opengraph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	delivery.DryRun = true
+	defer func() { delivery.DryRun = false }()
+
+	filename := filepath.Join(dir, "opengraph.json")
+	cache := openOpengraphCache(filename, time.Hour, time.Hour)
+	cache.put("https://conf.example", opengraph.New("https://conf.example"), pageMetadata{}, nil)
+	cache.save()
+
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected no cache file in dry-run, got %v", err)
+	}
+}
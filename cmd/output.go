@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var outputFlag = cli.StringFlag{
+	Name:  "output, o",
+	Usage: "File to write to instead of stdout, a directory getting one file per topic when several topics are given",
+}
+
+// volatileOutputLines are regenerated on every run without the content
+// changing: the feed level timestamps and the iCalendar DTSTAMP.
+var volatileOutputLines = regexp.MustCompile(`(?m)^(  <updated>.*</updated>|    <pubDate>.*</pubDate>|DTSTAMP:.*\r)\n`)
+
+// wrapOutputAction is wrapAction for commands generating a document per topic,
+// it accepts several topics and writes the documents to --output.
+func wrapOutputAction(generate func(topic string, conferences []confs.Conference, c *cli.Context) (string, error), extension func(c *cli.Context) string) func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		err := applyGlobalFlags(c)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+
+		topics := c.Args()
+		if len(topics) == 0 {
+			topics = []string{""}
+		}
+		output := c.String("output")
+		if len(topics) > 1 && output == "" {
+			return cli.NewExitError("Please provide an --output directory for several topics", 1)
+		}
+
+		for _, topic := range topics {
+			topic, err := validateTopicArgument(topic)
+			if err != nil {
+				return cli.NewExitError(err, 1)
+			}
+
+			conferences, err := fetchConferences(c, topic)
+			if err != nil {
+				return cli.NewExitError(err, 1)
+			}
+
+			content, err := generate(topic, conferences, c)
+			ogCache.save()
+			if err != nil {
+				return exitError(err)
+			}
+
+			if output == "" {
+				fmt.Print(content)
+				continue
+			}
+
+			filename := output
+			if info, err := os.Stat(output); len(topics) > 1 || err == nil && info.IsDir() {
+				err = os.MkdirAll(output, 0755)
+				if err != nil {
+					return exitError(err)
+				}
+				filename = filepath.Join(output, topic+extension(c))
+			}
+
+			err = writeOutputFile(filename, []byte(content))
+			if err != nil {
+				return exitError(err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// writeOutputFile atomically replaces the file unless its content did not
+// change, so the modification time served as Last-Modified stays the same.
+func writeOutputFile(filename string, content []byte) error {
+	current, err := ioutil.ReadFile(filename)
+	if err == nil && isSameOutput(current, content) {
+		return nil
+	}
+
+	if delivery.DryRun {
+		fmt.Fprintf(dryRunOutput, "File %s would change\n", filename)
+		return nil
+	}
+
+	return replaceFile(filename, content)
+}
+
+// replaceFile writes to a temporary file next to the file first, so readers
+// never see it half written.
+func replaceFile(filename string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+func isSameOutput(a []byte, b []byte) bool {
+	return bytes.Equal(volatileOutputLines.ReplaceAll(a, nil), volatileOutputLines.ReplaceAll(b, nil))
+}
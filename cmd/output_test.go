@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteOutputFileSkipsUnchangedContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "golang.ics")
+	err = writeOutputFile(filename, []byte("BEGIN:VCALENDAR\r\nDTSTAMP:20190501T100000Z\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	err = os.Chtimes(filename, past, past)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writeOutputFile(filename, []byte("BEGIN:VCALENDAR\r\nDTSTAMP:20190502T100000Z\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("Expected unchanged file not to be written, got %v", err)
+	}
+
+	err = writeOutputFile(filename, []byte("BEGIN:VCALENDAR\r\nDTSTAMP:20190502T100000Z\r\nSUMMARY:Go one\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(filename)
+	if string(content) != "BEGIN:VCALENDAR\r\nDTSTAMP:20190502T100000Z\r\nSUMMARY:Go one\r\nEND:VCALENDAR\r\n" {
+		t.Errorf("Expected changed file to be written, got %q", content)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected no temporary files left, got %d files", len(files))
+	}
+}
+
+func TestIsSameOutputIgnoresFeedTimestampsOnly(t *testing.T) {
+	feed := "<feed>\n  <updated>2019-05-01T10:00:00Z</updated>\n  <entry>\n    <updated>2019-05-01T10:00:00Z</updated>\n  </entry>\n</feed>\n"
+	regenerated := "<feed>\n  <updated>2019-05-02T10:00:00Z</updated>\n  <entry>\n    <updated>2019-05-01T10:00:00Z</updated>\n  </entry>\n</feed>\n"
+	changed := "<feed>\n  <updated>2019-05-02T10:00:00Z</updated>\n  <entry>\n    <updated>2019-05-02T10:00:00Z</updated>\n  </entry>\n</feed>\n"
+
+	if !isSameOutput([]byte(feed), []byte(regenerated)) {
+		t.Error("Expected feed level timestamp to be ignored")
+	}
+	if isSameOutput([]byte(feed), []byte(changed)) {
+		t.Error("Expected entry timestamp change to be detected")
+	}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+// exitCodePartialFailure is used when some conferences could not be posted
+// with --keep-going.
+const exitCodePartialFailure = 3
+
+var keepGoingFlag = cli.BoolFlag{
+	Name:   "keep-going",
+	Usage:  "Try to post every conference even if some of them fail",
+	EnvVar: "KEEP_GOING",
+}
+
+var reportFlag = cli.StringFlag{
+	Name:   "report",
+	Usage:  "Path of a JSON report about failed conferences, only with --keep-going",
+	EnvVar: "REPORT",
+}
+
+type deliveryFailure struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	StartDate string `json:"startDate"`
+	Error     string `json:"error"`
+	Permanent bool   `json:"permanent"`
+}
+
+// deliveryReport collects the failures of a run and keeps track of them in
+// the dead-letter queue, a nil report stops the run on the first failure.
+type deliveryReport struct {
+	Posted   int               `json:"posted"`
+	Failures []deliveryFailure `json:"failures"`
+
+	keepGoing   bool
+	deadLetters *deadLetterQueue
+}
+
+func newDeliveryReport(c *cli.Context) *deliveryReport {
+	return &deliveryReport{
+		Failures:    []deliveryFailure{},
+		keepGoing:   c.Bool("keep-going"),
+		deadLetters: openDeadLetterQueue(c),
+	}
+}
+
+func (r *deliveryReport) success(conference confs.Conference) {
+	if r == nil {
+		return
+	}
+
+	r.Posted++
+	r.deadLetters.delivered(conference)
+}
+
+// fail records the failure and reports whether the run should continue.
+func (r *deliveryReport) fail(conference confs.Conference, err error) bool {
+	if r == nil {
+		return false
+	}
+
+	r.deadLetters.failed(conference, err)
+	if !r.keepGoing {
+		return false
+	}
+
+	r.Failures = append(r.Failures, deliveryFailure{
+		Name:      conference.Name,
+		URL:       conference.URL,
+		StartDate: conference.StartDate,
+		Error:     err.Error(),
+		Permanent: isPermanentError(err),
+	})
+
+	return true
+}
+
+// finish logs the failures, writes the report file and returns an error with
+// a distinct exit code when anything failed.
+func (r *deliveryReport) finish(c *cli.Context) error {
+	if r == nil || !r.keepGoing {
+		return nil
+	}
+
+	for _, failure := range r.Failures {
+		log.Printf("Could not post %s (%s): %s", failure.Name, failure.URL, failure.Error)
+	}
+
+	if reportFile := c.String("report"); reportFile != "" {
+		reportString, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(reportFile, reportString, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d of %d conferences could not be posted", len(r.Failures), len(r.Failures)+r.Posted), exitCodePartialFailure)
+	}
+
+	return nil
+}
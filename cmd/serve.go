@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var serveContentTypes = map[string]string{
+	"atom": "application/atom+xml; charset=utf-8",
+	"rss":  "application/rss+xml; charset=utf-8",
+	"json": "application/feed+json; charset=utf-8",
+	"ical": "text/calendar; charset=utf-8",
+}
+
+func ServeCommand() cli.Command {
+	return cli.Command{
+		Name:   "serve",
+		Usage:  "serve feeds and calendars over HTTP",
+		Action: serveAction,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "listen",
+				Value:  ":8080",
+				Usage:  "Address to listen on",
+				EnvVar: "LISTEN",
+			},
+			cli.DurationFlag{
+				Name:   "refresh",
+				Value:  time.Hour,
+				Usage:  "How long conference data is cached before it is fetched again",
+				EnvVar: "REFRESH",
+			},
+		},
+	}
+}
+
+func serveAction(c *cli.Context) error {
+	err := applyGlobalFlags(c)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	server := newFeedServer(func(topic string) ([]confs.Conference, error) {
+		return fetchConferences(c, topic)
+	}, c.Duration("refresh"))
+
+	log.Printf("Serving feeds on %s", c.String("listen"))
+
+	// Rendering a feed may wait for the conference websites, hence the long
+	// write timeout
+	return exitError((&http.Server{
+		Addr:              c.String("listen"),
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      5 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}).ListenAndServe())
+}
+
+// feedServer serves /{format}/{topic} for every feed format and ical, the
+// conferences of a topic are fetched at most once per refresh interval. The
+// thumbnails are served under /thumbnails/ when they are generated.
+type feedServer struct {
+	fetch   func(topic string) ([]confs.Conference, error)
+	refresh time.Duration
+	mux     *http.ServeMux
+
+	// mutex guards the maps and the retry times, it is never held while
+	// fetching or rendering
+	mutex    sync.Mutex
+	topics   map[string]*cachedTopic
+	fetching map[string]*topicFetch
+}
+
+// cachedTopic is replaced as a whole when it is refreshed, only its responses
+// change afterwards.
+type cachedTopic struct {
+	conferences []confs.Conference
+	entries     []confs.FeedEntry
+	fetched     time.Time
+	// retryAt is when a failed refresh is attempted again
+	retryAt time.Time
+	// responses are the rendered documents by responseKey
+	responses map[string]cachedResponse
+}
+
+// topicFetch is a fetch in progress, requests for the same topic wait for it
+// instead of fetching again.
+type topicFetch struct {
+	done   chan struct{}
+	cached *cachedTopic
+	err    error
+}
+
+// refreshRetryInterval is the time between two attempts to refresh a topic
+// while its upstream is failing.
+const refreshRetryInterval = time.Minute
+
+// maxCachedResponses bounds the responses of a topic, as filter values are
+// chosen by the clients.
+const maxCachedResponses = 64
+
+type cachedResponse struct {
+	body []byte
+	etag string
+}
+
+func newFeedServer(fetch func(topic string) ([]confs.Conference, error), refresh time.Duration) *feedServer {
+	s := &feedServer{
+		fetch:    fetch,
+		refresh:  refresh,
+		mux:      http.NewServeMux(),
+		topics:   map[string]*cachedTopic{},
+		fetching: map[string]*topicFetch{},
+	}
+
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	for format := range serveContentTypes {
+		s.mux.Handle("/"+format+"/", s.handleFormat(format))
+	}
+	if images.Dir != "" {
+		s.mux.Handle("/thumbnails/", http.StripPrefix("/thumbnails/", http.FileServer(fileOnlyDir(images.Dir))))
+	}
+
+	return s
+}
+
+// fileOnlyDir serves the files of a directory without listing it.
+type fileOnlyDir string
+
+func (d fileOnlyDir) Open(name string) (http.File, error) {
+	file, err := http.Dir(d).Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err == nil && info.IsDir() {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (s *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *feedServer) handleFormat(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		topic, err := validateTopicArgument(strings.TrimPrefix(r.URL.Path, "/"+format+"/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		for _, region := range queryList(r, "region") {
+			if !confs.IsRegion(region) {
+				http.Error(w, fmt.Sprintf("Invalid region %s", region), http.StatusBadRequest)
+				return
+			}
+		}
+
+		response, err := s.render(format, topic, r)
+		if err != nil {
+			log.Printf("Could not serve %s: %s", r.URL, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("ETag", response.etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.refresh.Seconds())))
+		if matchesETag(r.Header.Get("If-None-Match"), response.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", serveContentTypes[format])
+		w.Header().Set("Content-Length", strconv.Itoa(len(response.body)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(response.body)
+		}
+	}
+}
+
+// render returns the cached document for the request or renders it from the
+// cached conferences.
+func (s *feedServer) render(format string, topic string, r *http.Request) (cachedResponse, error) {
+	cached, err := s.conferences(topic)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+
+	key := responseKey(format, r)
+	s.mutex.Lock()
+	response, found := cached.responses[key]
+	s.mutex.Unlock()
+	if found {
+		return response, nil
+	}
+
+	conferences := filterByQuery(cached.conferences, r)
+
+	var body string
+	if format == "ical" {
+		alarmDays, _ := strconv.Atoi(r.URL.Query().Get("cfp-alarm-days"))
+		body = generateICalendar(topic, conferences, queryBool(r, "cfp"), alarmDays, time.Now())
+	} else {
+		body, err = generateFeed(r.Context(), topic, conferences, cached.entries, format)
+		if err != nil {
+			return cachedResponse{}, err
+		}
+	}
+	if r.Context().Err() != nil {
+		return cachedResponse{}, r.Context().Err() // Not caching feeds missing opengraph data
+	}
+
+	ogCache.save()
+
+	// Volatile timestamps are left out so the ETag only changes with the content
+	response = cachedResponse{
+		body: []byte(body),
+		etag: fmt.Sprintf(`"%x"`, sha1.Sum(volatileOutputLines.ReplaceAll([]byte(body), nil))),
+	}
+
+	s.mutex.Lock()
+	if len(cached.responses) < maxCachedResponses {
+		cached.responses[key] = response
+	}
+	s.mutex.Unlock()
+
+	return response, nil
+}
+
+// conferences returns the cached conferences of the topic, fetching them again
+// once they are older than the refresh interval. Concurrent requests share one
+// fetch and stale data is served when fetching fails.
+func (s *feedServer) conferences(topic string) (*cachedTopic, error) {
+	s.mutex.Lock()
+	cached, found := s.topics[topic]
+	if found && (time.Since(cached.fetched) < s.refresh || time.Now().Before(cached.retryAt)) {
+		s.mutex.Unlock()
+		return cached, nil
+	}
+	if f, inProgress := s.fetching[topic]; inProgress {
+		s.mutex.Unlock()
+		<-f.done
+		return f.cached, f.err
+	}
+	f := &topicFetch{done: make(chan struct{})}
+	s.fetching[topic] = f
+	s.mutex.Unlock()
+
+	defer close(f.done)
+
+	conferences, err := s.fetch(topic)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.fetching, topic)
+
+	if err != nil {
+		if found {
+			log.Printf("Could not refresh %s, serving cached conferences: %s", topic, err)
+			cached.retryAt = time.Now().Add(refreshRetryInterval)
+			f.cached = cached
+			return cached, nil
+		}
+		f.err = err
+		return nil, err
+	}
+
+	entries := []confs.FeedEntry{}
+	if found {
+		entries = cached.entries
+	}
+	now := time.Now()
+	f.cached = &cachedTopic{
+		conferences: conferences,
+		entries:     updateFeedEntries(entries, conferences, now),
+		fetched:     now,
+		responses:   map[string]cachedResponse{},
+	}
+	s.topics[topic] = f.cached
+
+	return f.cached, nil
+}
+
+// responseKey identifies a document by the format and the query parameters it
+// depends on, normalized so their order and spelling do not matter.
+func responseKey(format string, r *http.Request) string {
+	query := url.Values{}
+	for _, name := range []string{"countries-blacklist", "region"} {
+		values := queryList(r, name)
+		sort.Strings(values)
+		for i, value := range values {
+			if i == 0 || value != values[i-1] {
+				query.Add(name, value)
+			}
+		}
+	}
+	if queryBool(r, "cfp-finished") {
+		query.Set("cfp-finished", "true")
+	}
+	if format == "ical" {
+		if queryBool(r, "cfp") {
+			query.Set("cfp", "true")
+		}
+		if alarmDays, _ := strconv.Atoi(r.URL.Query().Get("cfp-alarm-days")); alarmDays != 0 {
+			query.Set("cfp-alarm-days", strconv.Itoa(alarmDays))
+		}
+	}
+
+	return format + "?" + query.Encode()
+}
+
+// filterByQuery applies the global filters given as query parameters, lists
+// are comma separated.
+func filterByQuery(conferences []confs.Conference, r *http.Request) []confs.Conference {
+	return confs.FilterConferences(conferences,
+		confs.NewIsInFutureTest(),
+		confs.NewCFPFinishedTest(queryBool(r, "cfp-finished")),
+		confs.NewIsNotInBlacklistedCountryTest(queryList(r, "countries-blacklist")),
+		confs.NewIsInRegionTest(queryList(r, "region")),
+	)
+}
+
+func queryList(r *http.Request, name string) []string {
+	values := []string{}
+	for _, value := range r.URL.Query()[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+
+	return values
+}
+
+func queryBool(r *http.Request, name string) bool {
+	value, _ := strconv.ParseBool(r.URL.Query().Get(name))
+	return value
+}
+
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
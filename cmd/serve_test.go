@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func startFeedServer(t *testing.T) (*httptest.Server, *int) {
+	fetches := 0
+	server := httptest.NewServer(newFeedServer(func(topic string) ([]confs.Conference, error) {
+		fetches++
+		startDate := time.Now().AddDate(0, 1, 0).Format("2006-01-02")
+		return []confs.Conference{
+			confs.Conference{Name: "Go Berlin", URL: "http://127.0.0.1:1/berlin", StartDate: startDate, EndDate: startDate, City: "Berlin", Country: "Germany"},
+			confs.Conference{Name: "Go Tokyo", URL: "http://127.0.0.1:1/tokyo", StartDate: startDate, EndDate: startDate, City: "Tokyo", Country: "Japan"},
+		}, nil
+	}, time.Hour))
+
+	return server, &fetches
+}
+
+func get(t *testing.T, url string, etag string) (*http.Response, string) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	return resp, string(body)
+}
+
+func TestServeFiltersByQueryAndCaches(t *testing.T) {
+	server, fetches := startFeedServer(t)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/ical/golang?region=europe", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("Unexpected response %d %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, "SUMMARY:Go Berlin") || strings.Contains(body, "Go Tokyo") {
+		t.Errorf("Expected only european conferences, got %s", body)
+	}
+
+	resp, body = get(t, server.URL+"/rss/golang?countries-blacklist=Germany", "")
+	if resp.StatusCode != http.StatusOK || strings.Contains(body, "Go Berlin") || !strings.Contains(body, "Go Tokyo") {
+		t.Errorf("Expected blacklisted country to be filtered, got %s", body)
+	}
+
+	if *fetches != 1 {
+		t.Errorf("Expected conferences to be fetched once, got %d", *fetches)
+	}
+}
+
+func TestServeAnswersIfNoneMatch(t *testing.T) {
+	server, _ := startFeedServer(t)
+	defer server.Close()
+
+	resp, _ := get(t, server.URL+"/atom/golang", "")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("Expected ETag, got %d %v", resp.StatusCode, resp.Header)
+	}
+
+	resp, body := get(t, server.URL+"/atom/golang", etag)
+	if resp.StatusCode != http.StatusNotModified || body != "" {
+		t.Errorf("Expected 304 for matching ETag, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestServeRejectsInvalidRequests(t *testing.T) {
+	server, _ := startFeedServer(t)
+	defer server.Close()
+
+	for url, statusCode := range map[string]int{
+		"/healthz":                     http.StatusOK,
+		"/json/Go!":                    http.StatusNotFound,
+		"/json/golang?region=atlantis": http.StatusBadRequest,
+		"/pdf/golang":                  http.StatusNotFound,
+	} {
+		resp, _ := get(t, server.URL+url, "")
+		if resp.StatusCode != statusCode {
+			t.Errorf("Expected %d for %s, got %d", statusCode, url, resp.StatusCode)
+		}
+	}
+}
+
+func TestServeThumbnails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thumbnails")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "conference.jpg"), []byte("jpeg"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images.Dir = dir
+	defer func() { images.Dir = "" }()
+
+	server, _ := startFeedServer(t)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/thumbnails/conference.jpg", "")
+	if resp.StatusCode != http.StatusOK || body != "jpeg" {
+		t.Errorf("Expected thumbnail to be served, got %d %q", resp.StatusCode, body)
+	}
+
+	resp, body = get(t, server.URL+"/thumbnails/", "")
+	if resp.StatusCode != http.StatusNotFound || strings.Contains(body, "conference.jpg") {
+		t.Errorf("Expected thumbnail directory not to be listed, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestServeDoesNotRefetchAfterFailedRefresh(t *testing.T) {
+	fetches := 0
+	server := newFeedServer(func(topic string) ([]confs.Conference, error) {
+		fetches++
+		if fetches > 1 {
+			return nil, fmt.Errorf("upstream is down")
+		}
+		return []confs.Conference{}, nil
+	}, time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rss/golang", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected cached conferences to be served, got %d", recorder.Code)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	if fetches != 2 {
+		t.Errorf("Expected a single refresh until the retry interval passed, got %d fetches", fetches)
+	}
+}
+
+func TestServeNormalizesCacheKeys(t *testing.T) {
+	server := newFeedServer(func(topic string) ([]confs.Conference, error) {
+		return []confs.Conference{}, nil
+	}, time.Hour)
+
+	for _, uri := range []string{
+		"/rss/golang?region=europe,asia&unknown=1",
+		"/rss/golang?region=asia&region=europe&region=asia",
+		"/rss/golang?cfp=true&region=asia,europe&cfp-finished=false",
+	} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, uri, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Unexpected response %d for %s", recorder.Code, uri)
+		}
+	}
+	for i := 0; i < 2*maxCachedResponses; i++ {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/rss/golang?countries-blacklist=c%d", i), nil))
+	}
+
+	if responses := server.topics["golang"].responses; len(responses) != maxCachedResponses || responses["rss?region=asia&region=europe"].body == nil {
+		t.Errorf("Expected equivalent queries to share a response and the cache to be bounded, got %d responses", len(responses))
+	}
+}
+
+func TestServeDoesNotBlockOtherTopicsWhileFetching(t *testing.T) {
+	release := make(chan struct{})
+	fetches := make(chan string, 10)
+	server := httptest.NewServer(newFeedServer(func(topic string) ([]confs.Conference, error) {
+		fetches <- topic
+		if topic == "slow" {
+			<-release
+		}
+		return []confs.Conference{}, nil
+	}, time.Hour))
+	defer server.Close()
+	defer close(release)
+
+	for i := 0; i < 2; i++ {
+		go func() { _, _ = http.Get(server.URL + "/json/slow") }()
+	}
+	<-fetches
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = http.Get(server.URL + "/json/fast")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected other topic to be served while one is fetched")
+	}
+
+	if topic := <-fetches; topic != "fast" || len(fetches) != 0 {
+		t.Errorf("Expected the slow topic to be fetched once, got another fetch of %s", topic)
+	}
+}
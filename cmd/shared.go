@@ -1,15 +1,41 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
+	"net/http"
 	"regexp"
+	"strings"
 
+	"github.com/otiai10/opengraph"
 	"gopkg.in/urfave/cli.v1"
 
 	"github.com/flix-tech/confs.tech.push/confs"
 )
 
+var stateFileFlag = cli.StringFlag{
+	Name:  "state-file, s",
+	Value: "state.json",
+	Usage: "State file path",
+}
+
+var orderFlag = cli.StringFlag{
+	Name:   "order",
+	Value:  "date",
+	Usage:  "Order new conferences are posted in: date, cfp, country or none to keep the upstream order",
+	EnvVar: "ORDER",
+}
+
+var maxPerRunFlag = cli.IntFlag{
+	Name:   "max-per-run",
+	Usage:  "Maximum number of conferences posted per run, the rest is posted by the next runs, 0 means no limit",
+	EnvVar: "MAX_PER_RUN",
+}
+
 func validateTopicArgument(topic string) (string, error) {
 	if topic == "" {
 		return "", errors.New("Please provide conference topic")
@@ -25,30 +51,205 @@ func validateTopicArgument(topic string) (string, error) {
 
 func wrapAction(action func(topic string, conferences []confs.Conference, c *cli.Context) error) func(c *cli.Context) error {
 	return func (c *cli.Context) error {
-		topic, err := validateTopicArgument(c.Args().Get(0))
+		err := applyGlobalFlags(c)
 		if err != nil {
 			return cli.NewExitError(err, 1)
 		}
 
-		// Fetch conference data
-		conferences, err := confs.GetConferences(topic)
+		topic, err := validateTopicArgument(c.Args().Get(0))
 		if err != nil {
 			return cli.NewExitError(err, 1)
 		}
 
-		conferences = confs.FilterConferences(conferences,
-			confs.NewIsInFutureTest(),
-			confs.NewCFPFinishedTest(c.GlobalBool("cfp-finished")),
-			confs.NewIsNotInBlacklistedCountryTest(c.GlobalStringSlice("countries-blacklist")),
-		)
+		conferences, err := fetchConferences(c, topic)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
 
 		err = action(topic, conferences, c)
+		ogCache.save()
+		if delivery.DryRun {
+			printStateDiffs()
+		}
+
+		return exitError(err)
+	}
+}
+
+func applyGlobalFlags(c *cli.Context) error {
+	delivery.Retries = c.GlobalInt("retries")
+	delivery.Interval = c.GlobalDuration("rate-limit")
+	deliveryClient.Timeout = c.GlobalDuration("delivery-timeout")
+	delivery.DryRun = c.GlobalBool("dry-run")
+	enrichment.Workers = c.GlobalInt("opengraph-workers")
+	enrichment.Timeout = c.GlobalDuration("opengraph-timeout")
+	ogCache = openOpengraphCache(c.GlobalString("opengraph-cache"), c.GlobalDuration("opengraph-ttl"), c.GlobalDuration("opengraph-negative-ttl"))
+	images.MaxSize = c.GlobalInt64("image-max-size")
+	images.Dir = c.GlobalString("thumbnail-dir")
+	images.BaseURL = c.GlobalString("thumbnail-url")
+	images.Width = c.GlobalInt("thumbnail-width")
+
+	if images.Dir != "" && images.BaseURL == "" {
+		return errors.New("Please provide the --thumbnail-url the thumbnail directory is served at")
+	}
+
+	return nil
+}
+
+// fetchConferences fetches the conferences of the topic and applies the global
+// filters.
+func fetchConferences(c *cli.Context, topic string) ([]confs.Conference, error) {
+	for _, region := range c.GlobalStringSlice("region") {
+		if !confs.IsRegion(region) {
+			return nil, fmt.Errorf("Invalid region %s", region)
+		}
+	}
+
+	conferences, err := confs.GetConferences(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	return confs.FilterConferences(conferences,
+		confs.NewIsInFutureTest(),
+		confs.NewCFPFinishedTest(c.GlobalBool("cfp-finished")),
+		confs.NewIsNotInBlacklistedCountryTest(c.GlobalStringSlice("countries-blacklist")),
+		confs.NewIsInRegionTest(c.GlobalStringSlice("region")),
+	), nil
+}
+
+// exitError keeps the exit code of cli.ExitCoder errors and exits with 1 on
+// any other error.
+func exitError(err error) error {
+	if exitErr, ok := err.(cli.ExitCoder); ok {
+		return exitErr
+	}
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	return nil
+}
+
+// loadNewConferences returns the conferences which are neither in the state file
+// nor parked in the dead-letter queue, in posting order and limited to the
+// maximum per run, together with the already processed ones.
+func loadNewConferences(c *cli.Context, conferences []confs.Conference) ([]confs.Conference, []confs.ProcessedConference, error) {
+	processedConferences := confs.LoadState(c.String("state-file"))
+
+	conferences = confs.FilterConferences(conferences,
+		confs.NewTestConferenceIsNotOneOf(confs.Conferences(processedConferences)),
+		confs.NewTestConferenceIsNotOneOf(openDeadLetterQueue(c).parked()),
+	)
+
+	conferences, err := orderConferences(c, conferences)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if max := c.Int("max-per-run"); max > 0 && len(conferences) > max {
+		conferences = conferences[:max]
+	}
+
+	return conferences, processedConferences, nil
+}
+
+func orderConferences(c *cli.Context, conferences []confs.Conference) ([]confs.Conference, error) {
+	switch c.String("order") {
+	case "date":
+		return confs.SortConferences(conferences, confs.ByStartDate), nil
+	case "cfp":
+		return confs.SortConferences(conferences, confs.ByCFPEndDate), nil
+	case "country":
+		return confs.SortConferences(conferences, confs.ByCountry), nil
+	case "none":
+		return conferences, nil
+	default:
+		return nil, fmt.Errorf("Invalid order %s", c.String("order"))
+	}
+}
+
+// pushNewConferences calls push for every conference which is not in the state
+// file yet and records the successfully pushed ones in it. It stops on the first
+// failure unless --keep-going is set.
+func pushNewConferences(c *cli.Context, conferences []confs.Conference, push func(enrichedConference) error) error {
+	stateFile := c.String("state-file")
+	conferences, processedConferences, err := loadNewConferences(c, conferences)
+	if err != nil {
+		return err
+	}
+
+	report := newDeliveryReport(c)
+	for _, conference := range enrichConferences(context.Background(), conferences) {
+		err := push(conference)
 		if err != nil {
-			return cli.NewExitError(err, 1)
+			if report.fail(conference.Conference, err) {
+				continue
+			}
+
+			_ = saveState(stateFile, processedConferences)
+			return err
 		}
 
-		return nil
+		report.success(conference.Conference)
+		processedConferences = append(processedConferences, confs.ProcessedConference{Conference: conference.Conference})
+	}
+
+	err = saveState(stateFile, processedConferences)
+	if err != nil {
+		return err
 	}
+
+	return report.finish(c)
+}
+
+func postJSON(url string, payload interface{}, service string) error {
+	return sendJSON(http.MethodPost, url, nil, payload, nil, service)
+}
+
+// sendJSON sends payload as JSON and decodes the response into result unless
+// it is nil.
+func sendJSON(method string, url string, header http.Header, payload interface{}, result interface{}, service string) error {
+	payloadString, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(payloadString))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return sendRequest(req, result, service)
+}
+
+// formatHTMLBody escapes the conference data and the opengraph data as both
+// come from outside.
+func formatHTMLBody(c confs.Conference, og *opengraph.OpenGraph) string {
+	body := fmt.Sprintf("<p>%s・%s</p>", html.EscapeString(formatLocation(c)), html.EscapeString(formatDateRange(c)))
+	if og.Description != "" {
+		body += fmt.Sprintf("<p>%s</p>", html.EscapeString(og.Description))
+	}
+	if len(og.Image) > 0 {
+		body += fmt.Sprintf("<p><img src=\"%s\" alt=\"img\" /></p>", html.EscapeString(og.Image[0].URL))
+	}
+
+	return body
+}
+
+func formatTwitterURL(c confs.Conference) string {
+	handle := strings.TrimPrefix(strings.TrimSpace(c.Twitter), "@")
+	if handle == "" {
+		return ""
+	}
+
+	return "https://twitter.com/" + handle
 }
 
 func formatDateRange(c confs.Conference) string {
@@ -59,277 +260,279 @@ func formatDateRange(c confs.Conference) string {
 	return dateRange
 }
 
-func formatLocation(c confs.Conference) string {
-	flags := map[string]string{
-		"Ascension Island": "🇦🇨",
-		"Andorra": "🇦🇩",
-		"United Arab Emirates": "🇦🇪",
-		"Afghanistan": "🇦🇫",
-		"Antigua & Barbuda": "🇦🇬",
-		"Anguilla": "🇦🇮",
-		"Albania": "🇦🇱",
-		"Armenia": "🇦🇲",
-		"Angola": "🇦🇴",
-		"Antarctica": "🇦🇶",
-		"Argentina": "🇦🇷",
-		"American Samoa": "🇦🇸",
-		"Austria": "🇦🇹",
-		"Australia": "🇦🇺",
-		"Aruba": "🇦🇼",
-		"Åland Islands": "🇦🇽",
-		"Azerbaijan": "🇦🇿",
-		"Bosnia & Herzegovina": "🇧🇦",
-		"Barbados": "🇧🇧",
-		"Bangladesh": "🇧🇩",
-		"Belgium": "🇧🇪",
-		"Burkina Faso": "🇧🇫",
-		"Bulgaria": "🇧🇬",
-		"Bahrain": "🇧🇭",
-		"Burundi": "🇧🇮",
-		"Benin": "🇧🇯",
-		"St. Barthélemy": "🇧🇱",
-		"Bermuda": "🇧🇲",
-		"Brunei": "🇧🇳",
-		"Bolivia": "🇧🇴",
-		"Caribbean Netherlands": "🇧🇶",
-		"Brazil": "🇧🇷",
-		"Bahamas": "🇧🇸",
-		"Bhutan": "🇧🇹",
-		"Bouvet Island": "🇧🇻",
-		"Botswana": "🇧🇼",
-		"Belarus": "🇧🇾",
-		"Belize": "🇧🇿",
-		"Canada": "🇨🇦",
-		"Cocos (Keeling) Islands": "🇨🇨",
-		"Congo - Kinshasa": "🇨🇩",
-		"Central African Republic": "🇨🇫",
-		"Congo - Brazzaville": "🇨🇬",
-		"Switzerland": "🇨🇭",
-		"Côte d’Ivoire": "🇨🇮",
-		"Cook Islands": "🇨🇰",
-		"Chile": "🇨🇱",
-		"Cameroon": "🇨🇲",
-		"China": "🇨🇳",
-		"Colombia": "🇨🇴",
-		"Clipperton Island": "🇨🇵",
-		"Costa Rica": "🇨🇷",
-		"Cuba": "🇨🇺",
-		"Cape Verde": "🇨🇻",
-		"Curaçao": "🇨🇼",
-		"Christmas Island": "🇨🇽",
-		"Cyprus": "🇨🇾",
-		"Czechia": "🇨🇿",
-		"Czech Republic": "🇨🇿",
-		"Germany": "🇩🇪",
-		"Deutschland": "🇩🇪",
-		"Diego Garcia": "🇩🇬",
-		"Djibouti": "🇩🇯",
-		"Denmark": "🇩🇰",
-		"Dominica": "🇩🇲",
-		"Dominican Republic": "🇩🇴",
-		"Algeria": "🇩🇿",
-		"Ceuta & Melilla": "🇪🇦",
-		"Ecuador": "🇪🇨",
-		"Estonia": "🇪🇪",
-		"Egypt": "🇪🇬",
-		"Western Sahara": "🇪🇭",
-		"Eritrea": "🇪🇷",
-		"Spain": "🇪🇸",
-		"Ethiopia": "🇪🇹",
-		"European Union": "🇪🇺",
-		"Finland": "🇫🇮",
-		"Fiji": "🇫🇯",
-		"Falkland Islands": "🇫🇰",
-		"Micronesia": "🇫🇲",
-		"Faroe Islands": "🇫🇴",
-		"France": "🇫🇷",
-		"Gabon": "🇬🇦",
-		"United Kingdom": "🇬🇧",
-		"U.K.": "🇬🇧",
-		"Grenada": "🇬🇩",
-		"Georgia": "🇬🇪",
-		"French Guiana": "🇬🇫",
-		"Guernsey": "🇬🇬",
-		"Ghana": "🇬🇭",
-		"Gibraltar": "🇬🇮",
-		"Greenland": "🇬🇱",
-		"Gambia": "🇬🇲",
-		"Guinea": "🇬🇳",
-		"Guadeloupe": "🇬🇵",
-		"Equatorial Guinea": "🇬🇶",
-		"Greece": "🇬🇷",
-		"South Georgia & South Sandwich Islands": "🇬🇸",
-		"Guatemala": "🇬🇹",
-		"Guam": "🇬🇺",
-		"Guinea-Bissau": "🇬🇼",
-		"Guyana": "🇬🇾",
-		"Hong Kong SAR China": "🇭🇰",
-		"Heard & McDonald Islands": "🇭🇲",
-		"Honduras": "🇭🇳",
-		"Croatia": "🇭🇷",
-		"Haiti": "🇭🇹",
-		"Hungary": "🇭🇺",
-		"Canary Islands": "🇮🇨",
-		"Indonesia": "🇮🇩",
-		"Ireland": "🇮🇪",
-		"Israel": "🇮🇱",
-		"Isle of Man": "🇮🇲",
-		"India": "🇮🇳",
-		"British Indian Ocean Territory": "🇮🇴",
-		"Iraq": "🇮🇶",
-		"Iran": "🇮🇷",
-		"Iceland": "🇮🇸",
-		"Italy": "🇮🇹",
-		"Jersey": "🇯🇪",
-		"Jamaica": "🇯🇲",
-		"Jordan": "🇯🇴",
-		"Japan": "🇯🇵",
-		"Kenya": "🇰🇪",
-		"Kyrgyzstan": "🇰🇬",
-		"Cambodia": "🇰🇭",
-		"Kiribati": "🇰🇮",
-		"Comoros": "🇰🇲",
-		"St. Kitts & Nevis": "🇰🇳",
-		"North Korea": "🇰🇵",
-		"South Korea": "🇰🇷",
-		"Kuwait": "🇰🇼",
-		"Cayman Islands": "🇰🇾",
-		"Kazakhstan": "🇰🇿",
-		"Laos": "🇱🇦",
-		"Lebanon": "🇱🇧",
-		"St. Lucia": "🇱🇨",
-		"Liechtenstein": "🇱🇮",
-		"Sri Lanka": "🇱🇰",
-		"Liberia": "🇱🇷",
-		"Lesotho": "🇱🇸",
-		"Lithuania": "🇱🇹",
-		"Luxembourg": "🇱🇺",
-		"Latvia": "🇱🇻",
-		"Libya": "🇱🇾",
-		"Morocco": "🇲🇦",
-		"Monaco": "🇲🇨",
-		"Moldova": "🇲🇩",
-		"Montenegro": "🇲🇪",
-		"St. Martin": "🇲🇫",
-		"Madagascar": "🇲🇬",
-		"Marshall Islands": "🇲🇭",
-		"North Macedonia": "🇲🇰",
-		"Mali": "🇲🇱",
-		"Myanmar (Burma)": "🇲🇲",
-		"Mongolia": "🇲🇳",
-		"Macau Sar China": "🇲🇴",
-		"Northern Mariana Islands": "🇲🇵",
-		"Martinique": "🇲🇶",
-		"Mauritania": "🇲🇷",
-		"Montserrat": "🇲🇸",
-		"Malta": "🇲🇹",
-		"Mauritius": "🇲🇺",
-		"Maldives": "🇲🇻",
-		"Malawi": "🇲🇼",
-		"Mexico": "🇲🇽",
-		"Malaysia": "🇲🇾",
-		"Mozambique": "🇲🇿",
-		"Namibia": "🇳🇦",
-		"New Caledonia": "🇳🇨",
-		"Niger": "🇳🇪",
-		"Norfolk Island": "🇳🇫",
-		"Nigeria": "🇳🇬",
-		"Nicaragua": "🇳🇮",
-		"Netherlands": "🇳🇱",
-		"Norway": "🇳🇴",
-		"Nepal": "🇳🇵",
-		"Nauru": "🇳🇷",
-		"Niue": "🇳🇺",
-		"New Zealand": "🇳🇿",
-		"Oman": "🇴🇲",
-		"Panama": "🇵🇦",
-		"Peru": "🇵🇪",
-		"French Polynesia": "🇵🇫",
-		"Papua New Guinea": "🇵🇬",
-		"Philippines": "🇵🇭",
-		"Pakistan": "🇵🇰",
-		"Poland": "🇵🇱",
-		"St. Pierre & Miquelon": "🇵🇲",
-		"Pitcairn Islands": "🇵🇳",
-		"Puerto Rico": "🇵🇷",
-		"Palestinian Territories": "🇵🇸",
-		"Portugal": "🇵🇹",
-		"Palau": "🇵🇼",
-		"Paraguay": "🇵🇾",
-		"Qatar": "🇶🇦",
-		"Réunion": "🇷🇪",
-		"Romania": "🇷🇴",
-		"Serbia": "🇷🇸",
-		"Russia": "🇷🇺",
-		"Rwanda": "🇷🇼",
-		"Saudi Arabia": "🇸🇦",
-		"Solomon Islands": "🇸🇧",
-		"Seychelles": "🇸🇨",
-		"Sudan": "🇸🇩",
-		"Sweden": "🇸🇪",
-		"Singapore": "🇸🇬",
-		"St. Helena": "🇸🇭",
-		"Slovenia": "🇸🇮",
-		"Svalbard & Jan Mayen": "🇸🇯",
-		"Slovakia": "🇸🇰",
-		"Sierra Leone": "🇸🇱",
-		"San Marino": "🇸🇲",
-		"Senegal": "🇸🇳",
-		"Somalia": "🇸🇴",
-		"Suriname": "🇸🇷",
-		"South Sudan": "🇸🇸",
-		"São Tomé & Príncipe": "🇸🇹",
-		"El Salvador": "🇸🇻",
-		"Sint Maarten": "🇸🇽",
-		"Syria": "🇸🇾",
-		"Swaziland": "🇸🇿",
-		"Tristan Da Cunha": "🇹🇦",
-		"Turks & Caicos Islands": "🇹🇨",
-		"Chad": "🇹🇩",
-		"French Southern Territories": "🇹🇫",
-		"Togo": "🇹🇬",
-		"Thailand": "🇹🇭",
-		"Tajikistan": "🇹🇯",
-		"Tokelau": "🇹🇰",
-		"Timor-Leste": "🇹🇱",
-		"Turkmenistan": "🇹🇲",
-		"Tunisia": "🇹🇳",
-		"Tonga": "🇹🇴",
-		"Turkey": "🇹🇷",
-		"Trinidad & Tobago": "🇹🇹",
-		"Tuvalu": "🇹🇻",
-		"Taiwan": "🇹🇼",
-		"Tanzania": "🇹🇿",
-		"Ukraine": "🇺🇦",
-		"Uganda": "🇺🇬",
-		"U.S. Outlying Islands": "🇺🇲",
-		"United States": "🇺🇸",
-		"U.S.A.": "🇺🇸",
-		"USA": "🇺🇸",
-		"Uruguay": "🇺🇾",
-		"Uzbekistan": "🇺🇿",
-		"Vatican City": "🇻🇦",
-		"St. Vincent & Grenadines": "🇻🇨",
-		"Venezuela": "🇻🇪",
-		"British Virgin Islands": "🇻🇬",
-		"U.S. Virgin Islands": "🇻🇮",
-		"Vietnam": "🇻🇳",
-		"Vanuatu": "🇻🇺",
-		"Wallis & Futuna": "🇼🇫",
-		"Samoa": "🇼🇸",
-		"Kosovo": "🇽🇰",
-		"Yemen": "🇾🇪",
-		"Mayotte": "🇾🇹",
-		"South Africa": "🇿🇦",
-		"Zambia": "🇿🇲",
-		"Zimbabwe": "🇿🇼",
-		"England": "🏴󠁧󠁢󠁥󠁮󠁧󠁿",
-		"Scotland": "🏴󠁧󠁢󠁳󠁣󠁴󠁿",
-	}
+// countryFlags maps the countries as they are spelled in the conference data to
+// their flags, the regions of confs use the same spellings.
+var countryFlags = map[string]string{
+	"Ascension Island": "🇦🇨",
+	"Andorra": "🇦🇩",
+	"United Arab Emirates": "🇦🇪",
+	"Afghanistan": "🇦🇫",
+	"Antigua & Barbuda": "🇦🇬",
+	"Anguilla": "🇦🇮",
+	"Albania": "🇦🇱",
+	"Armenia": "🇦🇲",
+	"Angola": "🇦🇴",
+	"Antarctica": "🇦🇶",
+	"Argentina": "🇦🇷",
+	"American Samoa": "🇦🇸",
+	"Austria": "🇦🇹",
+	"Australia": "🇦🇺",
+	"Aruba": "🇦🇼",
+	"Åland Islands": "🇦🇽",
+	"Azerbaijan": "🇦🇿",
+	"Bosnia & Herzegovina": "🇧🇦",
+	"Barbados": "🇧🇧",
+	"Bangladesh": "🇧🇩",
+	"Belgium": "🇧🇪",
+	"Burkina Faso": "🇧🇫",
+	"Bulgaria": "🇧🇬",
+	"Bahrain": "🇧🇭",
+	"Burundi": "🇧🇮",
+	"Benin": "🇧🇯",
+	"St. Barthélemy": "🇧🇱",
+	"Bermuda": "🇧🇲",
+	"Brunei": "🇧🇳",
+	"Bolivia": "🇧🇴",
+	"Caribbean Netherlands": "🇧🇶",
+	"Brazil": "🇧🇷",
+	"Bahamas": "🇧🇸",
+	"Bhutan": "🇧🇹",
+	"Bouvet Island": "🇧🇻",
+	"Botswana": "🇧🇼",
+	"Belarus": "🇧🇾",
+	"Belize": "🇧🇿",
+	"Canada": "🇨🇦",
+	"Cocos (Keeling) Islands": "🇨🇨",
+	"Congo - Kinshasa": "🇨🇩",
+	"Central African Republic": "🇨🇫",
+	"Congo - Brazzaville": "🇨🇬",
+	"Switzerland": "🇨🇭",
+	"Côte d’Ivoire": "🇨🇮",
+	"Cook Islands": "🇨🇰",
+	"Chile": "🇨🇱",
+	"Cameroon": "🇨🇲",
+	"China": "🇨🇳",
+	"Colombia": "🇨🇴",
+	"Clipperton Island": "🇨🇵",
+	"Costa Rica": "🇨🇷",
+	"Cuba": "🇨🇺",
+	"Cape Verde": "🇨🇻",
+	"Curaçao": "🇨🇼",
+	"Christmas Island": "🇨🇽",
+	"Cyprus": "🇨🇾",
+	"Czechia": "🇨🇿",
+	"Czech Republic": "🇨🇿",
+	"Germany": "🇩🇪",
+	"Deutschland": "🇩🇪",
+	"Diego Garcia": "🇩🇬",
+	"Djibouti": "🇩🇯",
+	"Denmark": "🇩🇰",
+	"Dominica": "🇩🇲",
+	"Dominican Republic": "🇩🇴",
+	"Algeria": "🇩🇿",
+	"Ceuta & Melilla": "🇪🇦",
+	"Ecuador": "🇪🇨",
+	"Estonia": "🇪🇪",
+	"Egypt": "🇪🇬",
+	"Western Sahara": "🇪🇭",
+	"Eritrea": "🇪🇷",
+	"Spain": "🇪🇸",
+	"Ethiopia": "🇪🇹",
+	"European Union": "🇪🇺",
+	"Finland": "🇫🇮",
+	"Fiji": "🇫🇯",
+	"Falkland Islands": "🇫🇰",
+	"Micronesia": "🇫🇲",
+	"Faroe Islands": "🇫🇴",
+	"France": "🇫🇷",
+	"Gabon": "🇬🇦",
+	"United Kingdom": "🇬🇧",
+	"U.K.": "🇬🇧",
+	"Grenada": "🇬🇩",
+	"Georgia": "🇬🇪",
+	"French Guiana": "🇬🇫",
+	"Guernsey": "🇬🇬",
+	"Ghana": "🇬🇭",
+	"Gibraltar": "🇬🇮",
+	"Greenland": "🇬🇱",
+	"Gambia": "🇬🇲",
+	"Guinea": "🇬🇳",
+	"Guadeloupe": "🇬🇵",
+	"Equatorial Guinea": "🇬🇶",
+	"Greece": "🇬🇷",
+	"South Georgia & South Sandwich Islands": "🇬🇸",
+	"Guatemala": "🇬🇹",
+	"Guam": "🇬🇺",
+	"Guinea-Bissau": "🇬🇼",
+	"Guyana": "🇬🇾",
+	"Hong Kong SAR China": "🇭🇰",
+	"Heard & McDonald Islands": "🇭🇲",
+	"Honduras": "🇭🇳",
+	"Croatia": "🇭🇷",
+	"Haiti": "🇭🇹",
+	"Hungary": "🇭🇺",
+	"Canary Islands": "🇮🇨",
+	"Indonesia": "🇮🇩",
+	"Ireland": "🇮🇪",
+	"Israel": "🇮🇱",
+	"Isle of Man": "🇮🇲",
+	"India": "🇮🇳",
+	"British Indian Ocean Territory": "🇮🇴",
+	"Iraq": "🇮🇶",
+	"Iran": "🇮🇷",
+	"Iceland": "🇮🇸",
+	"Italy": "🇮🇹",
+	"Jersey": "🇯🇪",
+	"Jamaica": "🇯🇲",
+	"Jordan": "🇯🇴",
+	"Japan": "🇯🇵",
+	"Kenya": "🇰🇪",
+	"Kyrgyzstan": "🇰🇬",
+	"Cambodia": "🇰🇭",
+	"Kiribati": "🇰🇮",
+	"Comoros": "🇰🇲",
+	"St. Kitts & Nevis": "🇰🇳",
+	"North Korea": "🇰🇵",
+	"South Korea": "🇰🇷",
+	"Kuwait": "🇰🇼",
+	"Cayman Islands": "🇰🇾",
+	"Kazakhstan": "🇰🇿",
+	"Laos": "🇱🇦",
+	"Lebanon": "🇱🇧",
+	"St. Lucia": "🇱🇨",
+	"Liechtenstein": "🇱🇮",
+	"Sri Lanka": "🇱🇰",
+	"Liberia": "🇱🇷",
+	"Lesotho": "🇱🇸",
+	"Lithuania": "🇱🇹",
+	"Luxembourg": "🇱🇺",
+	"Latvia": "🇱🇻",
+	"Libya": "🇱🇾",
+	"Morocco": "🇲🇦",
+	"Monaco": "🇲🇨",
+	"Moldova": "🇲🇩",
+	"Montenegro": "🇲🇪",
+	"St. Martin": "🇲🇫",
+	"Madagascar": "🇲🇬",
+	"Marshall Islands": "🇲🇭",
+	"North Macedonia": "🇲🇰",
+	"Mali": "🇲🇱",
+	"Myanmar (Burma)": "🇲🇲",
+	"Mongolia": "🇲🇳",
+	"Macau Sar China": "🇲🇴",
+	"Northern Mariana Islands": "🇲🇵",
+	"Martinique": "🇲🇶",
+	"Mauritania": "🇲🇷",
+	"Montserrat": "🇲🇸",
+	"Malta": "🇲🇹",
+	"Mauritius": "🇲🇺",
+	"Maldives": "🇲🇻",
+	"Malawi": "🇲🇼",
+	"Mexico": "🇲🇽",
+	"Malaysia": "🇲🇾",
+	"Mozambique": "🇲🇿",
+	"Namibia": "🇳🇦",
+	"New Caledonia": "🇳🇨",
+	"Niger": "🇳🇪",
+	"Norfolk Island": "🇳🇫",
+	"Nigeria": "🇳🇬",
+	"Nicaragua": "🇳🇮",
+	"Netherlands": "🇳🇱",
+	"Norway": "🇳🇴",
+	"Nepal": "🇳🇵",
+	"Nauru": "🇳🇷",
+	"Niue": "🇳🇺",
+	"New Zealand": "🇳🇿",
+	"Oman": "🇴🇲",
+	"Panama": "🇵🇦",
+	"Peru": "🇵🇪",
+	"French Polynesia": "🇵🇫",
+	"Papua New Guinea": "🇵🇬",
+	"Philippines": "🇵🇭",
+	"Pakistan": "🇵🇰",
+	"Poland": "🇵🇱",
+	"St. Pierre & Miquelon": "🇵🇲",
+	"Pitcairn Islands": "🇵🇳",
+	"Puerto Rico": "🇵🇷",
+	"Palestinian Territories": "🇵🇸",
+	"Portugal": "🇵🇹",
+	"Palau": "🇵🇼",
+	"Paraguay": "🇵🇾",
+	"Qatar": "🇶🇦",
+	"Réunion": "🇷🇪",
+	"Romania": "🇷🇴",
+	"Serbia": "🇷🇸",
+	"Russia": "🇷🇺",
+	"Rwanda": "🇷🇼",
+	"Saudi Arabia": "🇸🇦",
+	"Solomon Islands": "🇸🇧",
+	"Seychelles": "🇸🇨",
+	"Sudan": "🇸🇩",
+	"Sweden": "🇸🇪",
+	"Singapore": "🇸🇬",
+	"St. Helena": "🇸🇭",
+	"Slovenia": "🇸🇮",
+	"Svalbard & Jan Mayen": "🇸🇯",
+	"Slovakia": "🇸🇰",
+	"Sierra Leone": "🇸🇱",
+	"San Marino": "🇸🇲",
+	"Senegal": "🇸🇳",
+	"Somalia": "🇸🇴",
+	"Suriname": "🇸🇷",
+	"South Sudan": "🇸🇸",
+	"São Tomé & Príncipe": "🇸🇹",
+	"El Salvador": "🇸🇻",
+	"Sint Maarten": "🇸🇽",
+	"Syria": "🇸🇾",
+	"Swaziland": "🇸🇿",
+	"Tristan Da Cunha": "🇹🇦",
+	"Turks & Caicos Islands": "🇹🇨",
+	"Chad": "🇹🇩",
+	"French Southern Territories": "🇹🇫",
+	"Togo": "🇹🇬",
+	"Thailand": "🇹🇭",
+	"Tajikistan": "🇹🇯",
+	"Tokelau": "🇹🇰",
+	"Timor-Leste": "🇹🇱",
+	"Turkmenistan": "🇹🇲",
+	"Tunisia": "🇹🇳",
+	"Tonga": "🇹🇴",
+	"Turkey": "🇹🇷",
+	"Trinidad & Tobago": "🇹🇹",
+	"Tuvalu": "🇹🇻",
+	"Taiwan": "🇹🇼",
+	"Tanzania": "🇹🇿",
+	"Ukraine": "🇺🇦",
+	"Uganda": "🇺🇬",
+	"U.S. Outlying Islands": "🇺🇲",
+	"United States": "🇺🇸",
+	"U.S.A.": "🇺🇸",
+	"USA": "🇺🇸",
+	"Uruguay": "🇺🇾",
+	"Uzbekistan": "🇺🇿",
+	"Vatican City": "🇻🇦",
+	"St. Vincent & Grenadines": "🇻🇨",
+	"Venezuela": "🇻🇪",
+	"British Virgin Islands": "🇻🇬",
+	"U.S. Virgin Islands": "🇻🇮",
+	"Vietnam": "🇻🇳",
+	"Vanuatu": "🇻🇺",
+	"Wallis & Futuna": "🇼🇫",
+	"Samoa": "🇼🇸",
+	"Kosovo": "🇽🇰",
+	"Yemen": "🇾🇪",
+	"Mayotte": "🇾🇹",
+	"South Africa": "🇿🇦",
+	"Zambia": "🇿🇲",
+	"Zimbabwe": "🇿🇼",
+	"England": "🏴󠁧󠁢󠁥󠁮󠁧󠁿",
+	"Scotland": "🏴󠁧󠁢󠁳󠁣󠁴󠁿",
+}
 
+func formatLocation(c confs.Conference) string {
 	location := fmt.Sprintf("%s, %s", c.City, c.Country)
 
-	flag, flagFound := flags[c.Country]
+	flag, flagFound := countryFlags[c.Country]
 	if (flagFound) {
 		location += " " + flag
 	}
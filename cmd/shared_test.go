@@ -1,7 +1,17 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
 
 	"github.com/flix-tech/confs.tech.push/confs"
 )
@@ -37,3 +47,123 @@ func TestFormatLocationWorksWithUnknownCountries(t *testing.T) {
 		t.Errorf("Got error when formating location: expected '%s', got '%s'", expected, location)
 	}
 }
+
+func newPushContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range []cli.Flag{stateFileFlag, orderFlag, maxPerRunFlag, keepGoingFlag, reportFlag, maxFailuresFlag, opsWebhookURLFlag} {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func TestFlaggedCountriesBelongToRegions(t *testing.T) {
+	countryRegions := confs.CountryRegions()
+	for country := range countryFlags {
+		if countryRegions[country] == "" {
+			t.Errorf("Expected %s to belong to a region", country)
+		}
+	}
+	for country := range countryRegions {
+		if _, found := countryFlags[country]; !found && country != "Online" {
+			t.Errorf("Expected %s of the regions to be spelled as a flagged country", country)
+		}
+	}
+}
+
+func TestPushNewConferencesOrdersAndLimits(t *testing.T) {
+	stateFile, err := ioutil.TempFile("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateFile.Close()
+	defer os.Remove(stateFile.Name())
+
+	date := func(days int) string { return time.Now().AddDate(0, 0, days).Format("2006-01-02") }
+	conferences := []confs.Conference{
+		confs.Conference{Name: "Go three", URL: "https://go3.com/", StartDate: date(30), City: "Berlin"},
+		confs.Conference{Name: "Go one", URL: "https://go1.com/", StartDate: date(10), City: "Berlin"},
+		confs.Conference{Name: "Go two", URL: "https://go2.com/", StartDate: date(20), City: "Berlin"},
+	}
+
+	c := newPushContext(t, "--state-file", stateFile.Name(), "--max-per-run", "2")
+	pushed := []string{}
+	push := func(conference enrichedConference) error {
+		pushed = append(pushed, conference.Name)
+		return nil
+	}
+
+	err = pushNewConferences(c, conferences, push)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed) != 2 || pushed[0] != "Go one" || pushed[1] != "Go two" {
+		t.Errorf("Expected the two earliest conferences to be pushed, got %v", pushed)
+	}
+	if state := confs.LoadState(stateFile.Name()); len(state) != 2 {
+		t.Errorf("Expected only pushed conferences in state, got %+v", state)
+	}
+
+	err = pushNewConferences(c, conferences, push)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed) != 3 || pushed[2] != "Go three" {
+		t.Errorf("Expected the remaining conference to be pushed by the next run, got %v", pushed)
+	}
+}
+
+func TestPushNewConferencesKeepsGoing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	date := time.Now().AddDate(0, 0, 10).Format("2006-01-02")
+	conferences := []confs.Conference{
+		confs.Conference{Name: "Go one", URL: "https://go1.com/", StartDate: date, City: "Berlin"},
+		confs.Conference{Name: "Go two", URL: "https://go2.com/", StartDate: date, City: "Berlin"},
+		confs.Conference{Name: "Go three", URL: "https://go3.com/", StartDate: date, City: "Berlin"},
+	}
+
+	c := newPushContext(t, "--state-file", dir+"/state.json", "--keep-going", "--report", dir+"/report.json")
+	err = pushNewConferences(c, conferences, func(conference enrichedConference) error {
+		if conference.Name == "Go two" {
+			return errors.New("malformed message")
+		}
+		return nil
+	})
+
+	exitErr, ok := err.(cli.ExitCoder)
+	if !ok || exitErr.ExitCode() != exitCodePartialFailure {
+		t.Fatalf("Expected partial failure exit code, got %v", err)
+	}
+	if state := confs.LoadState(dir + "/state.json"); len(state) != 2 {
+		t.Errorf("Expected successful conferences in state, got %+v", state)
+	}
+
+	reportString, _ := ioutil.ReadFile(dir + "/report.json")
+	report := deliveryReport{}
+	_ = json.Unmarshal(reportString, &report)
+	if report.Posted != 2 || len(report.Failures) != 1 || report.Failures[0].Error != "malformed message" {
+		t.Errorf("Unexpected report %s", reportString)
+	}
+}
+
+func TestFormatHTMLBodyEscapesOpengraphData(t *testing.T) {
+	og := opengraph.New("https://conf.example")
+	og.Description = `Go <script>alert("hi")</script>`
+	og.Image = []*opengraph.Image{&opengraph.Image{URL: `https://conf.example/a.png" onerror="alert(1)`}}
+
+	body := formatHTMLBody(confs.Conference{Name: "Go one", City: "Berlin", Country: "Germany", StartDate: "2026-11-01", EndDate: "2026-11-01"}, og)
+	if strings.Contains(body, "<script>") || strings.Contains(body, `" onerror="`) {
+		t.Errorf("Expected opengraph data to be escaped, got %s", body)
+	}
+	if !strings.Contains(body, "&lt;script&gt;") {
+		t.Errorf("Expected escaped description, got %s", body)
+	}
+}
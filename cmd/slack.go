@@ -1,11 +1,10 @@
 package cmd
 
 import (
-	"bytes"
-	"fmt"
-
 	"encoding/json"
-	"net/http"
+	"fmt"
+	"strings"
+	"text/template"
 
 	"gopkg.in/urfave/cli.v1"
 
@@ -17,7 +16,7 @@ func SlackCommand() cli.Command {
 		Name:   "slack",
 		Usage:  "push to slack",
 		Action: wrapAction(slackAction),
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:   "slack-url",
 				Usage:  "Slack Incoming Webhook url",
@@ -29,100 +28,226 @@ func SlackCommand() cli.Command {
 				EnvVar: "SLACK_CHANNEL",
 			},
 			cli.StringFlag{
-				Name:  "state-file, s",
-				Value: "state.json",
-				Usage: "State file path",
+				Name:   "slack-token",
+				Usage:  "Slack bot token, posts with the Web API instead of the Incoming Webhook when set",
+				EnvVar: "SLACK_TOKEN",
+			},
+			cli.StringFlag{
+				Name:   "slack-api-url",
+				Value:  "https://slack.com/api",
+				Usage:  "Slack Web API base url",
+				EnvVar: "SLACK_API_URL",
+			},
+			cli.IntFlag{
+				Name:   "cfp-reminder-days",
+				Value:  7,
+				Usage:  "Reply in thread this many days before the CFP closes, only with the bot token, 0 disables reminders",
+				EnvVar: "CFP_REMINDER_DAYS",
+			},
+			cli.StringFlag{
+				Name:   "slack-format",
+				Value:  "blocks",
+				Usage:  "Built-in message layout: blocks, or legacy attachments for older workspaces",
+				EnvVar: "SLACK_FORMAT",
 			},
-		},
+			templateFlag,
+			stateFileFlag,
+			orderFlag,
+			maxPerRunFlag,
+			keepGoingFlag,
+			reportFlag,
+			maxFailuresFlag,
+			opsWebhookURLFlag,
+		}, digestFlags...),
 	}
 }
 
-func slackAction(topic string, conferences []confs.Conference, c *cli.Context) error {
-	stateFile := c.String("state-file")
-	processedConferences := confs.LoadState(stateFile)
-
-	conferences = confs.FilterConferences(conferences,
-		confs.NewTestConferenceIsNotOneOf(processedConferences),
-	)
+const slackBlocksTemplate = `
+{{- $text := printf "*<%s|%s>*" .Conference.URL (slackEscape .Conference.Name) -}}
+{{- with .Opengraph.Description }}{{ $text = printf "%s\n%s" $text (slackEscape .) }}{{ end -}}
+{
+  "text": {{ printf "%s %s" .Conference.Name .Conference.URL | json }},
+  "blocks": [
+    {
+      "type": "section",
+      "text": {"type": "mrkdwn", "text": {{ truncate 2900 $text | json }}}
+      {{- with .Opengraph.Image }},
+      "accessory": {"type": "image", "image_url": {{ json (index . 0).URL }}, "alt_text": {{ json $.Conference.Name }}}
+      {{- end }}
+    },
+    {
+      "type": "context",
+      "elements": [
+        {"type": "mrkdwn", "text": {{ formatLocation .Conference | slackEscape | printf ":round_pushpin: %s" | json }}},
+        {"type": "mrkdwn", "text": {{ formatDateRange .Conference | printf ":calendar: %s" | json }}}
+      ]
+    },
+    {
+      "type": "actions",
+      "elements": [
+        {"type": "button", "text": {"type": "plain_text", "text": "Website"}, "url": {{ json .Conference.URL }}}
+        {{- with .Conference.CFPUrl }},
+        {"type": "button", "text": {"type": "plain_text", "text": "Submit talk (CFP)"}, "url": {{ json . }}}
+        {{- end }}
+        {{- with formatTwitterURL .Conference }},
+        {"type": "button", "text": {"type": "plain_text", "text": "Twitter"}, "url": {{ json . }}}
+        {{- end }}
+      ]
+    }
+  ],
+  "unfurl_links": false
+}
+`
+
+const slackLegacyTemplate = `{
+  "text": {{ printf "*%s*\n<%s>" .Conference.Name .Conference.URL | json }},
+  "attachments": [
+    {
+      "fields": [
+        {"title": "Location", "value": {{ formatLocation .Conference | json }}, "short": true},
+        {"title": "Dates", "value": {{ formatDateRange .Conference | json }}, "short": true}
+      ]
+    }
+  ],
+  "unfurl_links": true,
+  "mrkdwn": true
+}
+`
 
-	// Push to slack
+func slackAction(topic string, conferences []confs.Conference, c *cli.Context) error {
 	slackURL := c.String("slack-url")
-	if slackURL == "" {
-		return fmt.Errorf("Please provide slack Incoming Webhook url")
+	slackToken := c.String("slack-token")
+	if slackURL == "" && slackToken == "" {
+		return fmt.Errorf("Please provide slack Incoming Webhook url or bot token")
 	}
 	slackChannel := c.String("slack-channel")
+	if slackToken != "" && slackChannel == "" {
+		return fmt.Errorf("Please provide slack channel to post to with the bot token")
+	}
 
-	for _, c := range conferences {
-		err := pushToSlack(c, slackURL, slackChannel)
-		if err != nil {
-			_ = confs.SaveState(stateFile, processedConferences)
-			return err
+	defaultTemplate := slackBlocksTemplate
+	switch c.String("slack-format") {
+	case "blocks":
+	case "legacy":
+		defaultTemplate = slackLegacyTemplate
+	default:
+		return fmt.Errorf("Invalid slack format %s", c.String("slack-format"))
+	}
+
+	tmpl, err := loadTemplateOrDefault(c.String("template"), "slack", defaultTemplate)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("digest") {
+		if slackToken != "" {
+			return fmt.Errorf("Digest messages can only be posted with the Incoming Webhook")
 		}
 
-		processedConferences = append(processedConferences, c)
+		return pushDigest(c, topic, conferences, slackDigestLimit,
+			func(title string, groups []conferenceGroup) (interface{}, error) {
+				return newSlackDigestMessage(title, groups, slackChannel)
+			},
+			func(payload interface{}) error {
+				return postJSON(slackURL, payload, "slack")
+			},
+		)
 	}
 
-	return confs.SaveState(stateFile, processedConferences)
-}
+	if slackToken != "" {
+		api := slackAPI{
+			URL:     strings.TrimSuffix(c.String("slack-api-url"), "/"),
+			Token:   slackToken,
+			Channel: slackChannel,
+		}
+		return slackAPIAction(topic, conferences, c, api, tmpl)
+	}
 
-type slackField struct {
-	Title string `json:"title,omitempty"`
-	Value string `json:"value,omitempty"`
-	Short bool   `json:"short,omitempty"`
+	return pushNewConferences(c, conferences, func(conference enrichedConference) error {
+		return pushToSlack(templateData{
+			Topic:      topic,
+			Conference: conference.Conference,
+			Opengraph:  conference.Opengraph,
+			Metadata:   conference.Metadata,
+		}, tmpl, slackURL, slackChannel)
+	})
 }
 
-type slackAttachment struct {
-	Fallback  string       `json:"fallback,omitempty"`
-	Color     string       `json:"color,omitempty"`
-	Title     string       `json:"title,omitempty"`
-	TitleLink string       `json:"title_link,omitempty"`
-	Text      string       `json:"text,omitempty"`
-	Fields    []slackField `json:"fields,omitempty"`
-}
+func pushToSlack(data templateData, tmpl *template.Template, slackURL string, slackChannel string) error {
+	message, err := renderSlackMessage(data, tmpl, slackChannel)
+	if err != nil {
+		return err
+	}
 
-type slackMessage struct {
-	Channel     string            `json:"channel,omitempty"`
-	Text        string            `json:"text,omitempty"`
-	Attachments []slackAttachment `json:"attachments,omitempty"`
-	UnfurlLinks bool              `json:"unfurl_links,omitempty"`
-	Markdown    bool              `json:"mrkdwn,omitempty"`
+	return postJSON(slackURL, message, "slack")
 }
 
-func pushToSlack(c confs.Conference, slackURL string, slackChannel string) error {
-	message := slackMessage{
-		Channel: slackChannel,
-		Text:    fmt.Sprintf("*%s*\n<%s>", c.Name, c.URL),
-		Attachments: []slackAttachment{
-			slackAttachment{
-				Fields: []slackField{
-					slackField{
-						Title: "Location",
-						Value: formatLocation(c),
-						Short: true,
-					},
-					slackField{
-						Title: "Dates",
-						Value: formatDateRange(c),
-						Short: true,
-					},
-				},
-			},
-		},
-		UnfurlLinks: true,
-		Markdown:    true,
-	}
-	messageString, err := json.Marshal(message)
+func renderSlackMessage(data templateData, tmpl *template.Template, slackChannel string) (map[string]interface{}, error) {
+	body, err := renderJSONTemplate(tmpl, data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp, err := http.Post(slackURL, "application/json", bytes.NewBuffer(messageString))
+	message := map[string]interface{}{}
+	err = json.Unmarshal(body, &message)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("Got response code %d when sending message to slack", resp.StatusCode)
+	if slackChannel != "" {
+		message["channel"] = slackChannel
 	}
 
-	return nil
+	return message, nil
+}
+
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+const (
+	slackDigestLimit      = 40000
+	slackMaxBlocks        = 50
+	slackMaxSectionLength = 3000
+)
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackDigestMessage struct {
+	Channel     string       `json:"channel,omitempty"`
+	Text        string       `json:"text"`
+	Blocks      []slackBlock `json:"blocks"`
+	UnfurlLinks bool         `json:"unfurl_links"`
+}
+
+func newSlackDigestMessage(title string, groups []conferenceGroup, slackChannel string) (interface{}, error) {
+	blocks := []slackBlock{
+		slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: title}},
+	}
+
+	for _, group := range groups {
+		text := fmt.Sprintf("*%s*", slackEscape(group.Title))
+		for _, c := range group.Conferences {
+			text += fmt.Sprintf("\n• <%s|%s> — %s・%s", c.URL, slackEscape(c.Name), slackEscape(formatLocation(c)), formatDateRange(c))
+		}
+		if len(text) > slackMaxSectionLength {
+			return nil, errDigestTooLarge
+		}
+
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}})
+	}
+	if len(blocks) > slackMaxBlocks {
+		return nil, errDigestTooLarge
+	}
+
+	return slackDigestMessage{
+		Channel: slackChannel,
+		Text:    title,
+		Blocks:  blocks,
+	}, nil
 }
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+// slackAPI posts with chat.postMessage and chat.update, unlike Incoming
+// Webhooks it returns the message ts so it can be edited or replied to later.
+type slackAPI struct {
+	URL     string
+	Token   string
+	Channel string
+}
+
+type slackAPIResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+func (api slackAPI) call(method string, message map[string]interface{}) (*slackAPIResponse, error) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+api.Token)
+
+	var raw json.RawMessage
+	err := sendJSON(http.MethodPost, api.URL+"/"+method, header, message, &raw, "slack")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &slackAPIResponse{}
+	if delivery.DryRun || len(raw) == 0 || json.Unmarshal(raw, response) != nil {
+		// Without a readable response the message is posted but its ts unknown
+		return &slackAPIResponse{OK: true, Channel: api.Channel}, nil
+	}
+	if !response.OK {
+		return nil, fmt.Errorf("Got error %s when calling slack %s", response.Error, method)
+	}
+
+	return response, nil
+}
+
+func slackAPIAction(topic string, conferences []confs.Conference, c *cli.Context, api slackAPI, tmpl *template.Template) error {
+	stateFile := c.String("state-file")
+	processedConferences := confs.LoadState(stateFile)
+
+	report := newDeliveryReport(c)
+	conferences = confs.FilterConferences(conferences,
+		confs.NewTestConferenceIsNotOneOf(report.deadLetters.parked()),
+	)
+
+	conferences, err := orderConferences(c, conferences)
+	if err != nil {
+		return err
+	}
+
+	processedConferences, err = pushToSlackAPI(api, tmpl, topic, conferences, processedConferences, c.Int("cfp-reminder-days"), c.Int("max-per-run"), report)
+	saveErr := saveState(stateFile, processedConferences)
+	if err != nil {
+		return err
+	}
+	if saveErr != nil {
+		return saveErr
+	}
+
+	return report.finish(c)
+}
+
+// pushToSlackAPI posts up to maxNew new conferences, edits the messages of
+// conferences whose data changed and replies in thread when their CFP is about
+// to close. Failures are collected in the report if it is not nil.
+func pushToSlackAPI(api slackAPI, tmpl *template.Template, topic string, conferences []confs.Conference, processedConferences []confs.ProcessedConference, reminderDays int, maxNew int, report *deliveryReport) ([]confs.ProcessedConference, error) {
+	enriched := enrichSlackAPIMessages(conferences, processedConferences, maxNew)
+	posted := 0
+
+	for _, conference := range conferences {
+		// The prefetched data is only a guess of what is rendered, as failed
+		// posts are not counted against maxNew
+		e, found := enriched[conference]
+		i := findProcessedConference(processedConferences, conferences, conference)
+		if i < 0 {
+			if maxNew > 0 && posted >= maxNew {
+				continue
+			}
+
+			if !found {
+				e = enrichConferences(context.Background(), []confs.Conference{conference})[0]
+			}
+			processed, err := postToSlackAPI(api, tmpl, topic, e)
+			if err != nil {
+				if report.fail(conference, err) {
+					continue
+				}
+				return processedConferences, err
+			}
+
+			report.success(conference)
+			processedConferences = append(processedConferences, processed)
+			posted++
+			continue
+		}
+
+		if !found {
+			e = enrichedConference{Conference: conference, Opengraph: opengraph.New(conference.URL)}
+		}
+		err := followUpOnSlackAPI(api, tmpl, topic, e, &processedConferences[i], reminderDays)
+		if err != nil {
+			if report.fail(conference, err) {
+				continue
+			}
+			return processedConferences, err
+		}
+	}
+
+	return processedConferences, nil
+}
+
+// enrichSlackAPIMessages fetches the opengraph data of the conferences which
+// are going to be posted or edited.
+func enrichSlackAPIMessages(conferences []confs.Conference, processedConferences []confs.ProcessedConference, maxNew int) map[confs.Conference]enrichedConference {
+	rendered := []confs.Conference{}
+	posted := 0
+	for _, conference := range conferences {
+		i := findProcessedConference(processedConferences, conferences, conference)
+		if i < 0 && (maxNew <= 0 || posted < maxNew) {
+			rendered = append(rendered, conference)
+			posted++
+		}
+		if i >= 0 && processedConferences[i].MessageTS != "" && processedConferences[i].Conference != conference {
+			rendered = append(rendered, conference)
+		}
+	}
+
+	enriched := map[confs.Conference]enrichedConference{}
+	for _, e := range enrichConferences(context.Background(), rendered) {
+		enriched[e.Conference] = e
+	}
+
+	return enriched
+}
+
+func postToSlackAPI(api slackAPI, tmpl *template.Template, topic string, conference enrichedConference) (confs.ProcessedConference, error) {
+	message, err := renderSlackMessage(templateData{
+		Topic:      topic,
+		Conference: conference.Conference,
+		Opengraph:  conference.Opengraph,
+		Metadata:   conference.Metadata,
+	}, tmpl, api.Channel)
+	if err != nil {
+		return confs.ProcessedConference{}, err
+	}
+
+	response, err := api.call("chat.postMessage", message)
+	if err != nil {
+		return confs.ProcessedConference{}, err
+	}
+
+	return confs.ProcessedConference{
+		Conference:     conference.Conference,
+		MessageChannel: response.Channel,
+		MessageTS:      response.TS,
+	}, nil
+}
+
+// followUpOnSlackAPI edits the message of an already posted conference when it
+// changed and replies in thread when its CFP is about to close.
+func followUpOnSlackAPI(api slackAPI, tmpl *template.Template, topic string, enriched enrichedConference, p *confs.ProcessedConference, reminderDays int) error {
+	conference := enriched.Conference
+	if p.MessageTS == "" {
+		return nil // Posted with the Incoming Webhook, there is nothing to follow up on
+	}
+
+	if p.Conference != conference {
+		message, err := renderSlackMessage(templateData{
+			Topic:      topic,
+			Conference: conference,
+			Opengraph:  enriched.Opengraph,
+			Metadata:   enriched.Metadata,
+		}, tmpl, p.MessageChannel)
+		if err != nil {
+			return err
+		}
+		message["ts"] = p.MessageTS
+
+		_, err = api.call("chat.update", message)
+		if err != nil {
+			return err
+		}
+
+		if p.CFPEndDate != conference.CFPEndDate {
+			p.CFPReminded = false
+		}
+		p.Conference = conference
+	}
+
+	today := time.Now().Format("2006-01-02")
+	reminderDate := time.Now().AddDate(0, 0, reminderDays).Format("2006-01-02")
+	if reminderDays > 0 && !p.CFPReminded && p.CFPEndDate != "" && p.CFPEndDate >= today && p.CFPEndDate <= reminderDate {
+		_, err := api.call("chat.postMessage", map[string]interface{}{
+			"channel":   p.MessageChannel,
+			"thread_ts": p.MessageTS,
+			"text":      formatCFPReminder(conference),
+		})
+		if err != nil {
+			return err
+		}
+
+		p.CFPReminded = true
+	}
+
+	return nil
+}
+
+// findProcessedConference looks the conference up in the state. A conference
+// whose dates or location changed is found by URL and name, as long as the
+// record and the conference cannot belong to another edition of it.
+func findProcessedConference(processedConferences []confs.ProcessedConference, conferences []confs.Conference, c confs.Conference) int {
+	for i, p := range processedConferences {
+		if confs.IsSameConference(p.Conference, c) {
+			return i
+		}
+	}
+
+	found := -1
+	for i, p := range processedConferences {
+		if !resemblesConference(p.Conference, c) || hasSameConference(conferences, p.Conference) {
+			continue
+		}
+		if found >= 0 {
+			return -1
+		}
+		found = i
+	}
+	if found < 0 {
+		return -1
+	}
+
+	for _, other := range conferences {
+		if !confs.IsSameConference(other, c) && resemblesConference(processedConferences[found].Conference, other) && !hasSameProcessedConference(processedConferences, other) {
+			return -1
+		}
+	}
+
+	return found
+}
+
+func resemblesConference(a confs.Conference, b confs.Conference) bool {
+	return a.URL == b.URL && (a.Name == b.Name || a.StartDate == b.StartDate && a.City == b.City)
+}
+
+func hasSameConference(conferences []confs.Conference, c confs.Conference) bool {
+	for _, other := range conferences {
+		if confs.IsSameConference(other, c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasSameProcessedConference(processedConferences []confs.ProcessedConference, c confs.Conference) bool {
+	for _, p := range processedConferences {
+		if confs.IsSameConference(p.Conference, c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatCFPReminder(c confs.Conference) string {
+	cfpURL := c.CFPUrl
+	if cfpURL == "" {
+		cfpURL = c.URL
+	}
+
+	return fmt.Sprintf(":alarm_clock: The CFP closes on %s, <%s|submit a talk>!", c.CFPEndDate, cfpURL)
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+type slackAPICall struct {
+	Method  string
+	Message map[string]interface{}
+}
+
+func startSlackAPIServer(t *testing.T) (*httptest.Server, *[]slackAPICall) {
+	calls := []slackAPICall{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			_, _ = w.Write([]byte(`{"ok": false, "error": "not_authed"}`))
+			return
+		}
+
+		call := slackAPICall{Method: r.URL.Path[1:]}
+		_ = json.NewDecoder(r.Body).Decode(&call.Message)
+		calls = append(calls, call)
+
+		_, _ = w.Write([]byte(`{"ok": true, "channel": "C123", "ts": "1565000000.000100"}`))
+	}))
+
+	return server, &calls
+}
+
+func TestPushToSlackAPIPostsUpdatesAndReminds(t *testing.T) {
+	server, calls := startSlackAPIServer(t)
+	defer server.Close()
+
+	api := slackAPI{URL: server.URL, Token: "xoxb-test", Channel: "#conferences"}
+	tmpl := template.Must(template.New("slack").Funcs(templateFuncs).Parse(slackLegacyTemplate))
+	conference := confs.Conference{
+		Name:       "Go one",
+		URL:        "http://127.0.0.1:1/go1",
+		StartDate:  time.Now().AddDate(0, 1, 0).Format("2006-01-02"),
+		EndDate:    time.Now().AddDate(0, 1, 0).Format("2006-01-02"),
+		City:       "Berlin",
+		Country:    "Germany",
+		CFPEndDate: time.Now().AddDate(0, 0, 3).Format("2006-01-02"),
+	}
+
+	processed, err := pushToSlackAPI(api, tmpl, "golang", []confs.Conference{conference}, []confs.ProcessedConference{}, 7, 0, nil)
+	if err != nil {
+		t.Fatalf("Got error when posting to slack: %s", err)
+	}
+	if len(*calls) != 1 || (*calls)[0].Method != "chat.postMessage" || processed[0].MessageTS != "1565000000.000100" {
+		t.Fatalf("Expected a single chat.postMessage with stored ts, got %+v and %+v", *calls, processed)
+	}
+
+	conference.EndDate = time.Now().AddDate(0, 1, 1).Format("2006-01-02")
+	processed, err = pushToSlackAPI(api, tmpl, "golang", []confs.Conference{conference}, processed, 7, 0, nil)
+	if err != nil {
+		t.Fatalf("Got error when updating slack: %s", err)
+	}
+	if len(*calls) != 3 {
+		t.Fatalf("Expected chat.update and a thread reply, got %+v", *calls)
+	}
+	if update := (*calls)[1]; update.Method != "chat.update" || update.Message["ts"] != "1565000000.000100" || update.Message["channel"] != "C123" {
+		t.Errorf("Unexpected update call %+v", update)
+	}
+	if reply := (*calls)[2]; reply.Method != "chat.postMessage" || reply.Message["thread_ts"] != "1565000000.000100" {
+		t.Errorf("Unexpected reminder call %+v", reply)
+	}
+	if processed[0].EndDate != conference.EndDate || !processed[0].CFPReminded {
+		t.Errorf("Expected state to be updated, got %+v", processed[0])
+	}
+
+	_, err = pushToSlackAPI(api, tmpl, "golang", []confs.Conference{conference}, processed, 7, 0, nil)
+	if err != nil || len(*calls) != 3 {
+		t.Errorf("Expected no calls for unchanged conference, got %+v", *calls)
+	}
+}
+
+func TestPushToSlackAPIFailsOnAPIError(t *testing.T) {
+	server, _ := startSlackAPIServer(t)
+	defer server.Close()
+
+	api := slackAPI{URL: server.URL, Token: "wrong", Channel: "#conferences"}
+	tmpl := template.Must(template.New("slack").Funcs(templateFuncs).Parse(slackLegacyTemplate))
+
+	processed, err := pushToSlackAPI(api, tmpl, "golang", []confs.Conference{confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1"}}, []confs.ProcessedConference{}, 7, 0, nil)
+	if err == nil || len(processed) != 0 {
+		t.Errorf("Expected not_authed error and no state, got %v and %+v", err, processed)
+	}
+}
+
+func TestPushToSlackAPIRecordsPostWithUnreadableResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>ok</html>"))
+	}))
+	defer server.Close()
+
+	api := slackAPI{URL: server.URL, Token: "xoxb-test", Channel: "#conferences"}
+	tmpl := template.Must(template.New("slack").Funcs(templateFuncs).Parse(slackLegacyTemplate))
+
+	processed, err := pushToSlackAPI(api, tmpl, "golang", []confs.Conference{
+		confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1"},
+	}, []confs.ProcessedConference{}, 7, 0, nil)
+	if err != nil || len(processed) != 1 || processed[0].MessageTS != "" {
+		t.Errorf("Expected the post to be recorded without ts, got %+v %v", processed, err)
+	}
+}
+
+func TestPushToSlackAPIRespectsMaxNew(t *testing.T) {
+	server, calls := startSlackAPIServer(t)
+	defer server.Close()
+
+	api := slackAPI{URL: server.URL, Token: "xoxb-test", Channel: "#conferences"}
+	tmpl := template.Must(template.New("slack").Funcs(templateFuncs).Parse(slackLegacyTemplate))
+
+	processed, err := pushToSlackAPI(api, tmpl, "golang", []confs.Conference{
+		confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1"},
+		confs.Conference{Name: "Go two", URL: "http://127.0.0.1:1/go2"},
+	}, []confs.ProcessedConference{}, 7, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 || len(processed) != 1 || processed[0].Name != "Go one" {
+		t.Errorf("Expected only the first conference to be posted, got %+v", processed)
+	}
+}
+
+func TestPushToSlackAPIKeepsEditionsApart(t *testing.T) {
+	server, calls := startSlackAPIServer(t)
+	defer server.Close()
+
+	api := slackAPI{URL: server.URL, Token: "xoxb-test", Channel: "#conferences"}
+	tmpl := template.Must(template.New("slack").Funcs(templateFuncs).Parse(slackLegacyTemplate))
+	startDate := time.Now().AddDate(0, 1, 0).Format("2006-01-02")
+	berlin := confs.Conference{Name: "Go days", URL: "http://127.0.0.1:1/godays", StartDate: startDate, EndDate: startDate, City: "Berlin"}
+	munich := confs.Conference{Name: "Go days", URL: "http://127.0.0.1:1/godays", StartDate: startDate, EndDate: startDate, City: "Munich"}
+
+	processed, err := pushToSlackAPI(api, tmpl, "golang", []confs.Conference{berlin, munich}, []confs.ProcessedConference{}, 7, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 2 || len(processed) != 2 {
+		t.Fatalf("Expected both editions to be posted, got %+v", *calls)
+	}
+
+	_, err = pushToSlackAPI(api, tmpl, "golang", []confs.Conference{berlin, munich}, processed, 7, 0, nil)
+	if err != nil || len(*calls) != 2 {
+		t.Errorf("Expected no calls for unchanged editions, got %+v", *calls)
+	}
+
+	berlin.EndDate = time.Now().AddDate(0, 1, 1).Format("2006-01-02")
+	_, err = pushToSlackAPI(api, tmpl, "golang", []confs.Conference{berlin, munich}, processed, 7, 0, nil)
+	if err != nil || len(*calls) != 3 || (*calls)[2].Method != "chat.update" {
+		t.Errorf("Expected the changed edition to be updated, got %+v", *calls)
+	}
+}
+
+func TestPushToSlackAPIPostsNextConferenceAfterFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		if posts == 1 {
+			_, _ = w.Write([]byte(`{"ok": false, "error": "channel_not_found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok": true, "channel": "C123", "ts": "1565000000.000100"}`))
+	}))
+	defer server.Close()
+
+	api := slackAPI{URL: server.URL, Token: "xoxb-test", Channel: "#conferences"}
+	tmpl := template.Must(template.New("slack").Funcs(templateFuncs).Parse(slackBlocksTemplate))
+	report := &deliveryReport{keepGoing: true, deadLetters: &deadLetterQueue{filename: dir + "/state.dlq.json"}}
+
+	processed, err := pushToSlackAPI(api, tmpl, "golang", []confs.Conference{
+		confs.Conference{Name: "Go one", URL: "http://127.0.0.1:1/go1"},
+		confs.Conference{Name: "Go two", URL: "http://127.0.0.1:1/go2"},
+	}, []confs.ProcessedConference{}, 7, 1, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processed) != 1 || processed[0].Name != "Go two" || len(report.Failures) != 1 {
+		t.Errorf("Expected the second conference to be posted after the first failed, got %+v and %+v", processed, report.Failures)
+	}
+}
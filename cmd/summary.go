@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func SummaryCommand() cli.Command {
+	return cli.Command{
+		Name:   "summary",
+		Usage:  "post a summary of upcoming conferences or open CFPs to slack and msteams",
+		Action: wrapAction(summaryAction),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "summary",
+				Value:  "upcoming",
+				Usage:  "Summary kind: upcoming conferences or open cfp closing soon",
+				EnvVar: "SUMMARY",
+			},
+			cli.IntFlag{
+				Name:   "days",
+				Value:  30,
+				Usage:  "Number of days the summary looks ahead",
+				EnvVar: "SUMMARY_DAYS",
+			},
+			cli.StringFlag{
+				Name:   "digest-group",
+				Value:  "month",
+				Usage:  "Group upcoming conferences by month, country or cfp closing date",
+				EnvVar: "DIGEST_GROUP",
+			},
+			cli.StringFlag{
+				Name:   "slack-url",
+				Usage:  "Slack Incoming Webhook url",
+				EnvVar: "SLACK_URL",
+			},
+			cli.StringFlag{
+				Name:   "slack-channel, k",
+				Usage:  "Slack channel name",
+				EnvVar: "SLACK_CHANNEL",
+			},
+			cli.StringFlag{
+				Name:   "msteams-url",
+				Usage:  "Teams Incoming Webhook or Workflows webhook url",
+				EnvVar: "MSTEAMS_URL",
+			},
+			cli.StringFlag{
+				Name:   "msteams-format",
+				Value:  "legacy",
+				Usage:  "Message layout: legacy text for Office 365 connectors, or adaptive cards for Workflows webhooks",
+				EnvVar: "MSTEAMS_FORMAT",
+			},
+		},
+	}
+}
+
+// summaryAction posts all matching conferences every time, unlike the other
+// push commands it does not keep any state.
+func summaryAction(topic string, conferences []confs.Conference, c *cli.Context) error {
+	slackURL := c.String("slack-url")
+	msteamsURL := c.String("msteams-url")
+	if slackURL == "" && msteamsURL == "" {
+		return fmt.Errorf("Please provide slack or Teams webhook url")
+	}
+
+	_, msteamsRender, err := msteamsLayout(c.String("msteams-format"))
+	if err != nil {
+		return err
+	}
+
+	days := c.Int("days")
+	title, groupBy := "", c.String("digest-group")
+	switch c.String("summary") {
+	case "upcoming":
+		title = fmt.Sprintf("%s conferences in the next %d days", topic, days)
+		conferences = confs.FilterConferences(conferences, confs.NewStartsWithinDaysTest(days))
+	case "cfp":
+		title = fmt.Sprintf("Open %s CFPs closing in the next %d days", topic, days)
+		groupBy = "cfp"
+		conferences = confs.FilterConferences(conferences, confs.NewCFPClosesWithinDaysTest(days))
+	default:
+		return fmt.Errorf("Invalid summary %s", c.String("summary"))
+	}
+
+	if len(conferences) == 0 {
+		return nil
+	}
+
+	if slackURL != "" {
+		slackChannel := c.String("slack-channel")
+		err := pushSummary(title, conferences, groupBy, slackDigestLimit,
+			func(title string, groups []conferenceGroup) (interface{}, error) {
+				return newSlackDigestMessage(title, groups, slackChannel)
+			},
+			func(payload interface{}) error {
+				return postJSON(slackURL, payload, "slack")
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if msteamsURL != "" {
+		err := pushSummary(title, conferences, groupBy, msteamsDigestLimit, msteamsRender, func(payload interface{}) error {
+			return postJSON(msteamsURL, payload, "msteams")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func pushSummary(title string, conferences []confs.Conference, groupBy string, limit int, render digestRenderer, send func(interface{}) error) error {
+	messages, err := splitDigest(title, conferences, groupBy, limit, render)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		err := send(message.Payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
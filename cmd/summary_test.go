@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"flag"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestPushSummaryGroupsByCFPEndDate(t *testing.T) {
+	conferences := []confs.Conference{
+		confs.Conference{Name: "Go one", URL: "https://go1.com/", StartDate: "2019-08-20", EndDate: "2019-08-20", City: "Berlin", Country: "Germany", CFPEndDate: "2019-07-01"},
+		confs.Conference{Name: "Go two", URL: "https://go2.com/", StartDate: "2019-08-21", EndDate: "2019-08-21", City: "Mariupol", Country: "Ukraine", CFPEndDate: "2019-06-15"},
+	}
+
+	payloads := []interface{}{}
+	err := pushSummary("Open golang CFPs closing in the next 30 days", conferences, "cfp", msteamsDigestLimit, newMsteamsDigestMessage, func(payload interface{}) error {
+		payloads = append(payloads, payload)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "**Open golang CFPs closing in the next 30 days**\n\n" +
+		"**CFP closes 2019-06-15**\n\n- [Go two](https://go2.com/) — Mariupol, Ukraine 🇺🇦・2019-08-21\n\n" +
+		"**CFP closes 2019-07-01**\n\n- [Go one](https://go1.com/) — Berlin, Germany 🇩🇪・2019-08-20"
+	if len(payloads) != 1 || payloads[0].(msteamsMessage).Text != expected {
+		t.Errorf("Unexpected summary %+v", payloads)
+	}
+}
+
+func TestSummaryRejectsInvalidMsteamsFormat(t *testing.T) {
+	set := flag.NewFlagSet("summary", flag.ContinueOnError)
+	for _, f := range SummaryCommand().Flags {
+		f.Apply(set)
+	}
+	err := set.Parse([]string{"--msteams-url", "http://127.0.0.1:1/teams", "--msteams-format", "adaptiv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = summaryAction("golang", []confs.Conference{}, cli.NewContext(cli.NewApp(), set, nil))
+	if err == nil || err.Error() != "Invalid msteams format adaptiv" {
+		t.Errorf("Expected invalid msteams format error, got %v", err)
+	}
+}
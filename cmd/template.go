@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var templateFlag = cli.StringFlag{
+	Name:  "template, t",
+	Usage: "Path to a Go text/template file rendering the JSON message, the built-in layout is used when empty",
+}
+
+// templateData is passed to message templates.
+type templateData struct {
+	Topic      string
+	Conference confs.Conference
+	Opengraph  *opengraph.OpenGraph
+	Metadata   pageMetadata
+}
+
+var templateFuncs = template.FuncMap{
+	"formatLocation":   formatLocation,
+	"formatDateRange":  formatDateRange,
+	"formatTwitterURL": formatTwitterURL,
+	"slackEscape":      slackEscape,
+	"truncate":         truncate,
+	"json": func(v interface{}) (string, error) {
+		s, err := json.Marshal(v)
+		return string(s), err
+	},
+}
+
+func loadTemplate(filename string) (*template.Template, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.New(filepath.Base(filename)).Funcs(templateFuncs).Parse(string(content))
+}
+
+// loadTemplateOrDefault loads the template file or parses the built-in
+// template when no file is given.
+func loadTemplateOrDefault(filename string, name string, defaultTemplate string) (*template.Template, error) {
+	if filename != "" {
+		return loadTemplate(filename)
+	}
+
+	return template.New(name).Funcs(templateFuncs).Parse(defaultTemplate)
+}
+
+// renderJSONTemplate executes the template and makes sure the result is valid JSON.
+func renderJSONTemplate(tmpl *template.Template, data templateData) (json.RawMessage, error) {
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(out.Bytes()) {
+		return nil, fmt.Errorf("Template %s rendered invalid JSON for %s", tmpl.Name(), data.Conference.URL)
+	}
+
+	return json.RawMessage(out.Bytes()), nil
+}
+
+// truncate shortens the text to at most max characters ending with an
+// ellipsis, escaped entities like &amp; are not cut in half.
+func truncate(max int, text string) string {
+	runes := []rune(text)
+	if len(runes) <= max || max < 1 {
+		return text
+	}
+
+	cut := string(runes[:max-1])
+	if amp := strings.LastIndex(cut, "&"); amp > strings.LastIndex(cut, ";") {
+		cut = cut[:amp]
+	}
+
+	return cut + "…"
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otiai10/opengraph"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func goldenTemplateData() templateData {
+	og := opengraph.New("https://go1.com/")
+	og.Description = "The first \"Go\" conference"
+	og.Image = []*opengraph.Image{&opengraph.Image{URL: "https://go1.com/logo.png"}}
+
+	return templateData{
+		Topic: "golang",
+		Conference: confs.Conference{
+			Name:       "Go one",
+			URL:        "https://go1.com/",
+			StartDate:  "2019-08-20",
+			EndDate:    "2019-08-21",
+			City:       "Berlin",
+			Country:    "Germany",
+			CFPUrl:     "https://go1.com/cfp",
+			CFPEndDate: "2019-06-01",
+			Twitter:    "@goone",
+		},
+		Opengraph: og,
+	}
+}
+
+func assertGolden(t *testing.T, name string, actual []byte) {
+	golden := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		err := ioutil.WriteFile(golden, actual, 0644)
+		if err != nil {
+			t.Fatalf("Could not update %s: %s", golden, err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Could not read %s: %s", golden, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Rendered %s does not match %s:\n%s", name, golden, actual)
+	}
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	for name, defaultTemplate := range map[string]string{
+		"slack":            slackBlocksTemplate,
+		"slack_legacy":     slackLegacyTemplate,
+		"msteams":          msteamsTemplate,
+		"msteams_adaptive": msteamsAdaptiveTemplate,
+	} {
+		tmpl, err := loadTemplateOrDefault("", name, defaultTemplate)
+		if err != nil {
+			t.Fatalf("Could not parse %s template: %s", name, err)
+		}
+
+		body, err := renderJSONTemplate(tmpl, goldenTemplateData())
+		if err != nil {
+			t.Fatalf("Could not render %s template: %s", name, err)
+		}
+
+		assertGolden(t, name, body)
+	}
+}
+
+func TestCustomTemplate(t *testing.T) {
+	file, err := ioutil.TempFile("", "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	_, _ = file.WriteString(`{"text": {{ printf "%s — who's interested? 🙋" .Conference.Name | json }}}`)
+	file.Close()
+
+	tmpl, err := loadTemplateOrDefault(file.Name(), "msteams", msteamsTemplate)
+	if err != nil {
+		t.Fatalf("Could not load template: %s", err)
+	}
+
+	body, err := renderJSONTemplate(tmpl, goldenTemplateData())
+	if err != nil {
+		t.Fatalf("Could not render template: %s", err)
+	}
+	if string(body) != `{"text": "Go one — who's interested? 🙋"}` {
+		t.Errorf("Unexpected rendered template %s", body)
+	}
+}
+
+func TestSlackTemplateTruncatesLongDescriptions(t *testing.T) {
+	data := goldenTemplateData()
+	data.Opengraph.Description = strings.Repeat("Gophers & friends ", 300)
+
+	tmpl, err := loadTemplateOrDefault("", "slack", slackBlocksTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := renderJSONTemplate(tmpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var message struct {
+		Blocks []struct {
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+		} `json:"blocks"`
+	}
+	err = json.Unmarshal(body, &message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	section := []rune(message.Blocks[0].Text.Text)
+	if len(section) > 2900 || !strings.HasSuffix(string(section), "…") {
+		t.Errorf("Expected section text to be truncated to 2900 characters, got %d", len(section))
+	}
+}
+
+func TestTruncateKeepsEntities(t *testing.T) {
+	if s := truncate(7, "Go &amp; Rust"); s != "Go …" {
+		t.Errorf("Expected entity not to be cut, got %q", s)
+	}
+	if s := truncate(20, "Go &amp; Rust"); s != "Go &amp; Rust" {
+		t.Errorf("Expected short text to be kept, got %q", s)
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+// webhookHeaderFlag has no EnvVar as the flag would split it on commas, which
+// header values may contain. WEBHOOK_HEADERS is read by webhookHeaders.
+var webhookHeaderFlag = cli.StringSliceFlag{
+	Name:  "webhook-header, H",
+	Usage: "Additional request header in 'Name: value' form, can be repeated",
+}
+
+func WebhookCommand() cli.Command {
+	return cli.Command{
+		Name:   "webhook",
+		Usage:  "push to a generic webhook using a body template",
+		Action: wrapAction(webhookAction),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "webhook-url",
+				Usage:  "Webhook url",
+				EnvVar: "WEBHOOK_URL",
+			},
+			cli.StringFlag{
+				Name:   "webhook-template",
+				Usage:  "Path to a Go text/template file rendering the JSON request body",
+				EnvVar: "WEBHOOK_TEMPLATE",
+			},
+			cli.StringFlag{
+				Name:   "webhook-method",
+				Value:  http.MethodPost,
+				Usage:  "HTTP method",
+				EnvVar: "WEBHOOK_METHOD",
+			},
+			webhookHeaderFlag,
+			stateFileFlag,
+			orderFlag,
+			maxPerRunFlag,
+			keepGoingFlag,
+			reportFlag,
+			maxFailuresFlag,
+			opsWebhookURLFlag,
+		},
+	}
+}
+
+type webhookConfig struct {
+	URL      string
+	Method   string
+	Header   http.Header
+	Template *template.Template
+}
+
+func webhookAction(topic string, conferences []confs.Conference, c *cli.Context) error {
+	config := webhookConfig{
+		URL:    c.String("webhook-url"),
+		Method: strings.ToUpper(c.String("webhook-method")),
+		Header: http.Header{},
+	}
+	if config.URL == "" {
+		return fmt.Errorf("Please provide webhook url")
+	}
+	if c.String("webhook-template") == "" {
+		return fmt.Errorf("Please provide webhook body template")
+	}
+
+	tmpl, err := loadTemplate(c.String("webhook-template"))
+	if err != nil {
+		return err
+	}
+	config.Template = tmpl
+
+	for _, header := range webhookHeaders(c) {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Invalid webhook header %s", header)
+		}
+		config.Header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	return pushNewConferences(c, conferences, func(conference enrichedConference) error {
+		return pushToWebhook(templateData{
+			Topic:      topic,
+			Conference: conference.Conference,
+			Opengraph:  conference.Opengraph,
+			Metadata:   conference.Metadata,
+		}, config)
+	})
+}
+
+// webhookHeaders returns the --webhook-header values, or the lines of
+// WEBHOOK_HEADERS when the flag is not given.
+func webhookHeaders(c *cli.Context) []string {
+	if headers := c.StringSlice("webhook-header"); len(headers) > 0 {
+		return headers
+	}
+
+	headers := []string{}
+	for _, header := range strings.Split(os.Getenv("WEBHOOK_HEADERS"), "\n") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+
+	return headers
+}
+
+func pushToWebhook(data templateData, config webhookConfig) error {
+	body, err := renderJSONTemplate(config.Template, data)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(config.Method, config.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range config.Header {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	return sendRequest(req, nil, "webhook")
+}
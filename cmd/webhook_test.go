@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"text/template"
+
+	"github.com/otiai10/opengraph"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/flix-tech/confs.tech.push/confs"
+)
+
+func TestPushToWebhook(t *testing.T) {
+	var method, token string
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		token = r.Header.Get("X-Token")
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	tmpl := template.Must(template.New("body").Funcs(templateFuncs).Parse(
+		`{"title": {{ json .Conference.Name }}, "location": {{ formatLocation .Conference | json }}, "topic": "{{ .Topic }}"}`,
+	))
+
+	err := pushToWebhook(templateData{
+		Topic: "golang",
+		Conference: confs.Conference{
+			Name:      `Go "two"`,
+			URL:       "https://go2.com/",
+			StartDate: "2019-08-21",
+			EndDate:   "2019-08-21",
+			City:      "Mariupol",
+			Country:   "Ukraine",
+		},
+		Opengraph: opengraph.New("https://go2.com/"),
+	}, webhookConfig{
+		URL:      server.URL,
+		Method:   http.MethodPut,
+		Header:   http.Header{"X-Token": []string{"secret"}},
+		Template: tmpl,
+	})
+
+	if err != nil {
+		t.Fatalf("Got error when pushing to webhook: %s", err)
+	}
+	if method != http.MethodPut || token != "secret" {
+		t.Errorf("Expected PUT request with X-Token header, got %s with '%s'", method, token)
+	}
+	if received["title"] != `Go "two"` || received["location"] != "Mariupol, Ukraine 🇺🇦" || received["topic"] != "golang" {
+		t.Errorf("Unexpected webhook body %v", received)
+	}
+}
+
+func TestPushToWebhookRejectsInvalidJSON(t *testing.T) {
+	tmpl := template.Must(template.New("body").Funcs(templateFuncs).Parse(`{"title": "{{ .Conference.Name }}`))
+
+	err := pushToWebhook(templateData{Conference: confs.Conference{Name: "Go one"}}, webhookConfig{
+		URL:      "http://127.0.0.1:1/",
+		Method:   http.MethodPost,
+		Template: tmpl,
+	})
+
+	if err == nil {
+		t.Errorf("Expected error for invalid JSON body")
+	}
+}
+
+func TestWebhookHeadersFromEnvironmentKeepCommas(t *testing.T) {
+	os.Setenv("WEBHOOK_HEADERS", "Authorization: Bearer secret\nAccept: application/json, text/plain\n")
+	defer os.Unsetenv("WEBHOOK_HEADERS")
+
+	set := flag.NewFlagSet("webhook", flag.ContinueOnError)
+	webhookHeaderFlag.Apply(set)
+	_ = set.Parse([]string{})
+
+	headers := webhookHeaders(cli.NewContext(cli.NewApp(), set, nil))
+	if len(headers) != 2 || headers[1] != "Accept: application/json, text/plain" {
+		t.Errorf("Expected one header per line, got %q", headers)
+	}
+
+	_ = set.Parse([]string{"-H", "X-Tags: go, rust"})
+	headers = webhookHeaders(cli.NewContext(cli.NewApp(), set, nil))
+	if len(headers) != 1 || headers[0] != "X-Tags: go, rust" {
+		t.Errorf("Expected flag to take precedence, got %q", headers)
+	}
+}
@@ -1,8 +1,12 @@
 package confs
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"encoding/json"
@@ -21,18 +25,22 @@ type Conference struct {
 	Twitter    string
 }
 
+// client fetches the conference data, a stalled upstream must not hang the
+// commands or the feed server.
+var client = &http.Client{Timeout: 30 * time.Second}
+
 func GetConferences(topic string) ([]Conference, error) {
 	var conferences []Conference
 
 	url := fmt.Sprintf("https://raw.githubusercontent.com/tech-conferences/conference-data/master/conferences/%d/%s.json", time.Now().Year(), topic)
-	resp, err := http.Get(url)
+	resp, err := client.Get(url)
 	if err != nil {
 		return conferences, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
 		return conferences, fmt.Errorf("Got response code %d when calling %s", resp.StatusCode, url)
 	}
-	defer resp.Body.Close()
 
 	err = json.NewDecoder(resp.Body).Decode(&conferences)
 	if err != nil {
@@ -42,23 +50,160 @@ func GetConferences(topic string) ([]Conference, error) {
 	return conferences, nil
 }
 
-func LoadState(finename string) []Conference {
+// ProcessedConference is a conference stored in the state file. Destinations
+// which can follow up on their messages keep the message reference in it.
+type ProcessedConference struct {
+	Conference
+	MessageChannel string `json:",omitempty"`
+	MessageTS      string `json:",omitempty"`
+	CFPReminded    bool   `json:",omitempty"`
+}
+
+func LoadState(finename string) []ProcessedConference {
 	state, err := ioutil.ReadFile(finename)
 	if err != nil {
-		return []Conference{}
+		return []ProcessedConference{}
 	}
 
-	var conferences = []Conference{}
+	var conferences = []ProcessedConference{}
 	json.Unmarshal(state, &conferences)
 
-	return FilterConferences(conferences, NewIsInFutureTest())
+	isInFuture := NewIsInFutureTest()
+	out := []ProcessedConference{}
+	for _, c := range conferences {
+		if isInFuture(c.Conference) {
+			out = append(out, c)
+		}
+	}
+
+	return out
 }
 
-func SaveState(filename string, conferences []Conference) error {
+func SaveState(filename string, conferences []ProcessedConference) error {
 	stateString, err := json.Marshal(conferences)
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(filename, stateString, 0644)
+	return writeStateFile(filename, stateString)
+}
+
+// writeStateFile writes to a temporary file first so the state is never left
+// half written, the temporary file is unique so runs sharing a state directory
+// do not collide.
+func writeStateFile(filename string, content []byte) error {
+	if filename == "" {
+		return errors.New("Please provide a state file")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+func Conferences(processed []ProcessedConference) []Conference {
+	conferences := []Conference{}
+	for _, p := range processed {
+		conferences = append(conferences, p.Conference)
+	}
+
+	return conferences
+}
+
+// FeedEntry records when a feed first published a conference and when its
+// data last changed, so regenerated feeds keep stable timestamps.
+type FeedEntry struct {
+	Conference
+	FirstSeen time.Time
+	Updated   time.Time
+}
+
+func LoadFeedState(filename string) []FeedEntry {
+	state, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return []FeedEntry{}
+	}
+
+	var entries = []FeedEntry{}
+	json.Unmarshal(state, &entries)
+
+	isInFuture := NewIsInFutureTest()
+	out := []FeedEntry{}
+	for _, e := range entries {
+		if isInFuture(e.Conference) {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+func SaveFeedState(filename string, entries []FeedEntry) error {
+	stateString, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	return writeStateFile(filename, stateString)
+}
+
+// DeadLetter is a conference which could not be posted, it is parked and not
+// posted anymore once it failed too often.
+type DeadLetter struct {
+	Conference
+	Failures  int
+	LastError string
+	Parked    bool
+}
+
+// DeadLetterFile returns the path of the dead letters kept next to the state file.
+func DeadLetterFile(stateFile string) string {
+	return strings.TrimSuffix(stateFile, filepath.Ext(stateFile)) + ".dlq.json"
+}
+
+func LoadDeadLetters(filename string) []DeadLetter {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return []DeadLetter{}
+	}
+
+	var deadLetters = []DeadLetter{}
+	json.Unmarshal(content, &deadLetters)
+
+	// Past conferences are not posted anymore, keeping them would only grow the file
+	isInFuture := NewIsInFutureTest()
+	out := []DeadLetter{}
+	for _, d := range deadLetters {
+		if isInFuture(d.Conference) {
+			out = append(out, d)
+		}
+	}
+
+	return out
+}
+
+func SaveDeadLetters(filename string, deadLetters []DeadLetter) error {
+	content, err := json.Marshal(deadLetters)
+	if err != nil {
+		return err
+	}
+
+	return writeStateFile(filename, content)
 }
@@ -0,0 +1,40 @@
+package confs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveStateReplacesFileAtomically(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "state.json")
+	conference := Conference{Name: "Go one", StartDate: time.Now().AddDate(0, 1, 0).Format("2006-01-02")}
+	for i := 0; i < 2; i++ {
+		err = SaveState(filename, []ProcessedConference{ProcessedConference{Conference: conference}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 || files[0].Name() != "state.json" {
+		t.Errorf("Expected only the state file to be left, got %d files", len(files))
+	}
+	if state := LoadState(filename); len(state) != 1 || state[0].Name != "Go one" {
+		t.Errorf("Unexpected state %+v", state)
+	}
+}
+
+func TestSaveStateRejectsEmptyFilename(t *testing.T) {
+	if SaveState("", []ProcessedConference{}) == nil {
+		t.Errorf("Expected empty state file name to be rejected")
+	}
+}
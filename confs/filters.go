@@ -20,6 +20,17 @@ func NewCFPFinishedTest(enableTest bool) ConferenceTest {
 	return func(c Conference) bool { return c.CFPEndDate < today }
 }
 
+func NewStartsWithinDaysTest(days int) ConferenceTest {
+	until := time.Now().AddDate(0, 0, days).Format("2006-01-02")
+	return func(c Conference) bool { return c.StartDate <= until }
+}
+
+func NewCFPClosesWithinDaysTest(days int) ConferenceTest {
+	today := time.Now().Format("2006-01-02")
+	until := time.Now().AddDate(0, 0, days).Format("2006-01-02")
+	return func(c Conference) bool { return c.CFPEndDate >= today && c.CFPEndDate <= until }
+}
+
 func NewIsNotInBlacklistedCountryTest(countriesBlacklist []string) ConferenceTest {
 	return func(c Conference) bool {
 		for _, blacklistedCountry := range countriesBlacklist {
@@ -35,7 +46,7 @@ func NewIsNotInBlacklistedCountryTest(countriesBlacklist []string) ConferenceTes
 func NewTestConferenceIsNotOneOf(conferenceBlacklist []Conference) ConferenceTest {
 	return func(c Conference) bool {
 		for _, p := range conferenceBlacklist {
-			if c.URL == p.URL && c.StartDate == p.StartDate && c.City == p.City {
+			if IsSameConference(c, p) {
 				return false
 			}
 		}
@@ -44,6 +55,12 @@ func NewTestConferenceIsNotOneOf(conferenceBlacklist []Conference) ConferenceTes
 	}
 }
 
+// IsSameConference tells if both are the same edition of a conference even if
+// some of the details changed.
+func IsSameConference(a, b Conference) bool {
+	return a.URL == b.URL && a.StartDate == b.StartDate && a.City == b.City
+}
+
 func FilterConferences(conferences []Conference, tests ...ConferenceTest) []Conference {
 	out := []Conference{}
 	test := combineConferenceFilters(tests)
@@ -80,3 +80,42 @@ func TestFilterNotBlacklistedCountry(t *testing.T) {
 		t.Errorf("Not blacklisted country did not pass test")
 	}
 }
+
+func TestFilterStartsWithinDays(t *testing.T) {
+	test := NewStartsWithinDaysTest(30)
+
+	if !test(Conference{Name: "Soon", StartDate: time.Now().AddDate(0, 0, 10).Format("2006-01-02")}) {
+		t.Errorf("Conference in 10 days must pass 30 days test")
+	}
+	if test(Conference{Name: "Later", StartDate: time.Now().AddDate(0, 0, 40).Format("2006-01-02")}) {
+		t.Errorf("Conference in 40 days must fail 30 days test")
+	}
+}
+
+func TestFilterCFPClosesWithinDays(t *testing.T) {
+	test := NewCFPClosesWithinDaysTest(30)
+
+	if !test(Conference{Name: "Closing", CFPEndDate: time.Now().AddDate(0, 0, 10).Format("2006-01-02")}) {
+		t.Errorf("CFP closing in 10 days must pass 30 days test")
+	}
+	if test(Conference{Name: "Closed", CFPEndDate: time.Now().AddDate(0, 0, -1).Format("2006-01-02")}) {
+		t.Errorf("Closed CFP must fail 30 days test")
+	}
+	if test(Conference{Name: "no CFP"}) {
+		t.Errorf("Conference without CFP must fail 30 days test")
+	}
+}
+
+func TestFilterIsInRegion(t *testing.T) {
+	test := NewIsInRegionTest([]string{"Europe", "online"})
+
+	if !test(Conference{Name: "Go Berlin", Country: "Germany"}) || !test(Conference{Name: "Go London", Country: "England"}) || !test(Conference{Name: "Go remote", Country: "Online"}) {
+		t.Errorf("Conferences in Europe and online must pass Europe and online test")
+	}
+	if test(Conference{Name: "Go Tokyo", Country: "Japan"}) {
+		t.Errorf("Conference in Japan must fail Europe test")
+	}
+	if !NewIsInRegionTest([]string{})(Conference{Name: "Go Tokyo", Country: "Japan"}) {
+		t.Errorf("Every conference must pass test without regions")
+	}
+}
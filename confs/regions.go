@@ -0,0 +1,94 @@
+package confs
+
+import "strings"
+
+// regions maps the region names to the countries as they are spelled in the
+// conference data and in the flags of the messages.
+var regions = map[string][]string{
+	"europe": {
+		"Åland Islands", "Albania", "Andorra", "Armenia", "Austria", "Azerbaijan", "Belarus", "Belgium",
+		"Bosnia & Herzegovina", "Bulgaria", "Canary Islands", "Ceuta & Melilla", "Croatia", "Cyprus", "Czechia",
+		"Czech Republic", "Denmark", "Deutschland", "England", "Estonia", "European Union", "Faroe Islands",
+		"Finland", "France", "Georgia", "Germany", "Gibraltar", "Greece", "Guernsey", "Hungary", "Iceland",
+		"Ireland", "Isle of Man", "Italy", "Jersey", "Kosovo", "Latvia", "Liechtenstein", "Lithuania",
+		"Luxembourg", "Malta", "Moldova", "Monaco", "Montenegro", "Netherlands", "North Macedonia", "Norway",
+		"Poland", "Portugal", "Romania", "Russia", "San Marino", "Scotland", "Serbia", "Slovakia", "Slovenia",
+		"Spain", "Svalbard & Jan Mayen", "Sweden", "Switzerland", "Turkey", "U.K.", "Ukraine", "United Kingdom",
+		"Vatican City",
+	},
+	"north-america": {
+		"Anguilla", "Antigua & Barbuda", "Aruba", "Bahamas", "Barbados", "Belize", "Bermuda",
+		"British Virgin Islands", "Canada", "Caribbean Netherlands", "Cayman Islands", "Clipperton Island",
+		"Costa Rica", "Cuba", "Curaçao", "Dominica", "Dominican Republic", "El Salvador", "Greenland", "Grenada",
+		"Guadeloupe", "Guatemala", "Haiti", "Honduras", "Jamaica", "Martinique", "Mexico", "Montserrat",
+		"Nicaragua", "Panama", "Puerto Rico", "Sint Maarten", "St. Barthélemy", "St. Kitts & Nevis", "St. Lucia",
+		"St. Martin", "St. Pierre & Miquelon", "St. Vincent & Grenadines", "Trinidad & Tobago",
+		"Turks & Caicos Islands", "U.S. Virgin Islands", "U.S.A.", "United States", "USA",
+	},
+	"south-america": {
+		"Argentina", "Bolivia", "Brazil", "Chile", "Colombia", "Ecuador", "Falkland Islands", "French Guiana",
+		"Guyana", "Paraguay", "Peru", "South Georgia & South Sandwich Islands", "Suriname", "Uruguay", "Venezuela",
+	},
+	"asia": {
+		"Afghanistan", "Bahrain", "Bangladesh", "Bhutan", "British Indian Ocean Territory", "Brunei", "Cambodia",
+		"China", "Diego Garcia", "Hong Kong SAR China", "India", "Indonesia", "Iran", "Iraq", "Israel", "Japan",
+		"Jordan", "Kazakhstan", "Kuwait", "Kyrgyzstan", "Laos", "Lebanon", "Macau Sar China", "Malaysia",
+		"Maldives", "Mongolia", "Myanmar (Burma)", "Nepal", "North Korea", "Oman", "Pakistan",
+		"Palestinian Territories", "Philippines", "Qatar", "Saudi Arabia", "Singapore", "South Korea", "Sri Lanka",
+		"Syria", "Taiwan", "Tajikistan", "Thailand", "Timor-Leste", "Turkmenistan", "United Arab Emirates",
+		"Uzbekistan", "Vietnam", "Yemen",
+	},
+	"africa": {
+		"Algeria", "Angola", "Ascension Island", "Benin", "Botswana", "Bouvet Island", "Burkina Faso", "Burundi",
+		"Cameroon", "Cape Verde", "Central African Republic", "Chad", "Comoros", "Congo - Brazzaville",
+		"Congo - Kinshasa", "Côte d’Ivoire", "Djibouti", "Egypt", "Equatorial Guinea", "Eritrea", "Ethiopia",
+		"French Southern Territories", "Gabon", "Gambia", "Ghana", "Guinea", "Guinea-Bissau", "Kenya", "Lesotho",
+		"Liberia", "Libya", "Madagascar", "Malawi", "Mali", "Mauritania", "Mauritius", "Mayotte", "Morocco",
+		"Mozambique", "Namibia", "Niger", "Nigeria", "Réunion", "Rwanda", "São Tomé & Príncipe", "Senegal",
+		"Seychelles", "Sierra Leone", "Somalia", "South Africa", "South Sudan", "St. Helena", "Sudan", "Swaziland",
+		"Tanzania", "Togo", "Tristan Da Cunha", "Tunisia", "Uganda", "Western Sahara", "Zambia", "Zimbabwe",
+	},
+	"oceania": {
+		"American Samoa", "Antarctica", "Australia", "Christmas Island", "Cocos (Keeling) Islands", "Cook Islands",
+		"Fiji", "French Polynesia", "Guam", "Heard & McDonald Islands", "Kiribati", "Marshall Islands",
+		"Micronesia", "Nauru", "New Caledonia", "New Zealand", "Niue", "Norfolk Island",
+		"Northern Mariana Islands", "Palau", "Papua New Guinea", "Pitcairn Islands", "Samoa", "Solomon Islands",
+		"Tokelau", "Tonga", "Tuvalu", "U.S. Outlying Islands", "Vanuatu", "Wallis & Futuna",
+	},
+	"online": {
+		"Online",
+	},
+}
+
+// CountryRegions returns the region of every country.
+func CountryRegions() map[string]string {
+	countries := map[string]string{}
+	for region, names := range regions {
+		for _, country := range names {
+			countries[country] = region
+		}
+	}
+
+	return countries
+}
+
+// IsRegion tells if the region is known.
+func IsRegion(region string) bool {
+	_, found := regions[strings.ToLower(region)]
+	return found
+}
+
+// NewIsInRegionTest passes conferences in one of the regions, all conferences
+// pass if no region is given.
+func NewIsInRegionTest(regionNames []string) ConferenceTest {
+	countries := map[string]bool{}
+	for _, region := range regionNames {
+		for _, country := range regions[strings.ToLower(region)] {
+			countries[country] = true
+		}
+	}
+
+	return func(c Conference) bool {
+		return len(regionNames) == 0 || countries[c.Country]
+	}
+}
@@ -0,0 +1,44 @@
+package confs
+
+import (
+	"sort"
+)
+
+type ConferenceOrder func(a, b Conference) bool
+
+func ByStartDate(a, b Conference) bool {
+	if a.StartDate != b.StartDate {
+		return a.StartDate < b.StartDate
+	}
+
+	return a.Name < b.Name
+}
+
+// ByCFPEndDate puts conferences with the closest CFP deadline first and the
+// ones without CFP last.
+func ByCFPEndDate(a, b Conference) bool {
+	if a.CFPEndDate != b.CFPEndDate {
+		if a.CFPEndDate == "" || b.CFPEndDate == "" {
+			return b.CFPEndDate == ""
+		}
+		return a.CFPEndDate < b.CFPEndDate
+	}
+
+	return ByStartDate(a, b)
+}
+
+func ByCountry(a, b Conference) bool {
+	if a.Country != b.Country {
+		return a.Country < b.Country
+	}
+
+	return ByStartDate(a, b)
+}
+
+// SortConferences returns a sorted copy of the conferences.
+func SortConferences(conferences []Conference, less ConferenceOrder) []Conference {
+	sorted := append([]Conference{}, conferences...)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+
+	return sorted
+}
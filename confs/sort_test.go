@@ -0,0 +1,29 @@
+package confs
+
+import (
+	"testing"
+)
+
+func TestSortConferencesByCFPEndDate(t *testing.T) {
+	sorted := SortConferences([]Conference{
+		Conference{Name: "no CFP", StartDate: "2019-08-01"},
+		Conference{Name: "CFP later", StartDate: "2019-08-02", CFPEndDate: "2019-07-01"},
+		Conference{Name: "CFP sooner", StartDate: "2019-08-03", CFPEndDate: "2019-06-01"},
+	}, ByCFPEndDate)
+
+	if sorted[0].Name != "CFP sooner" || sorted[1].Name != "CFP later" || sorted[2].Name != "no CFP" {
+		t.Errorf("Unexpected order %+v", sorted)
+	}
+}
+
+func TestSortConferencesByCountry(t *testing.T) {
+	sorted := SortConferences([]Conference{
+		Conference{Name: "Go Ukraine", Country: "Ukraine", StartDate: "2019-08-01"},
+		Conference{Name: "Go Berlin", Country: "Germany", StartDate: "2019-08-03"},
+		Conference{Name: "Go Munich", Country: "Germany", StartDate: "2019-08-02"},
+	}, ByCountry)
+
+	if sorted[0].Name != "Go Munich" || sorted[1].Name != "Go Berlin" || sorted[2].Name != "Go Ukraine" {
+		t.Errorf("Unexpected order %+v", sorted)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"gopkg.in/urfave/cli.v1"
 
@@ -22,17 +23,106 @@ func main() {
 			Usage:  "Countries to be blocked",
 			EnvVar: "COUNTRIES_BLACKLIST",
 		},
+		cli.StringSliceFlag{
+			Name:   "region",
+			Usage:  "Regions to post conferences of: europe, north-america, south-america, asia, africa, oceania or online",
+			EnvVar: "REGION",
+		},
 		cli.BoolFlag{
 			Name:   "cfp-finished",
 			Usage:  "Post only conferences with CallForPapers stage finished",
 			EnvVar: "CFP_FINISHED",
 		},
+		cli.IntFlag{
+			Name:   "retries",
+			Value:  4,
+			Usage:  "Number of times a message is sent again after a transient failure",
+			EnvVar: "RETRIES",
+		},
+		cli.DurationFlag{
+			Name:   "delivery-timeout",
+			Value:  30 * time.Second,
+			Usage:  "Maximum time a destination is waited for before the message is sent again",
+			EnvVar: "DELIVERY_TIMEOUT",
+		},
+		cli.DurationFlag{
+			Name:   "rate-limit",
+			Value:  time.Second,
+			Usage:  "Minimum time between two messages sent to the same destination",
+			EnvVar: "RATE_LIMIT",
+		},
+		cli.StringFlag{
+			Name:   "opengraph-cache",
+			Value:  "opengraph-cache.json",
+			Usage:  "File caching the opengraph data of the conference websites, empty disables the cache",
+			EnvVar: "OPENGRAPH_CACHE",
+		},
+		cli.DurationFlag{
+			Name:   "opengraph-ttl",
+			Value:  24 * time.Hour,
+			Usage:  "How long opengraph data is cached",
+			EnvVar: "OPENGRAPH_TTL",
+		},
+		cli.DurationFlag{
+			Name:   "opengraph-negative-ttl",
+			Value:  time.Hour,
+			Usage:  "How long failures to fetch opengraph data are cached",
+			EnvVar: "OPENGRAPH_NEGATIVE_TTL",
+		},
+		cli.IntFlag{
+			Name:   "opengraph-workers",
+			Value:  8,
+			Usage:  "Number of conference websites fetched in parallel for their opengraph data",
+			EnvVar: "OPENGRAPH_WORKERS",
+		},
+		cli.DurationFlag{
+			Name:   "opengraph-timeout",
+			Value:  10 * time.Second,
+			Usage:  "Maximum time a conference website is waited for",
+			EnvVar: "OPENGRAPH_TIMEOUT",
+		},
+		cli.Int64Flag{
+			Name:   "image-max-size",
+			Value:  1 << 20,
+			Usage:  "Maximum size in bytes of the embedded preview images, larger ones are skipped unless thumbnails are generated",
+			EnvVar: "IMAGE_MAX_SIZE",
+		},
+		cli.StringFlag{
+			Name:   "thumbnail-dir",
+			Usage:  "Directory the resized preview images are written to, thumbnails are not generated when empty",
+			EnvVar: "THUMBNAIL_DIR",
+		},
+		cli.StringFlag{
+			Name:   "thumbnail-url",
+			Usage:  "Public url the thumbnail directory is served at, e.g. by the serve command under /thumbnails",
+			EnvVar: "THUMBNAIL_URL",
+		},
+		cli.IntFlag{
+			Name:   "thumbnail-width",
+			Value:  600,
+			Usage:  "Width of the thumbnails in pixels",
+			EnvVar: "THUMBNAIL_WIDTH",
+		},
+		cli.BoolFlag{
+			Name:   "dry-run",
+			Usage:  "Print the messages and the state changes instead of sending and saving them",
+			EnvVar: "DRY_RUN",
+		},
 	}
 
 	app.Commands = []cli.Command{
 		cmd.AtomCommand(),
+		cmd.IcalCommand(),
+		cmd.ServeCommand(),
 		cmd.SlackCommand(),
 		cmd.MsteamsCommand(),
+		cmd.GooglechatCommand(),
+		cmd.EmailCommand(),
+		cmd.MastodonCommand(),
+		cmd.WebhookCommand(),
+		cmd.SummaryCommand(),
+		cmd.DeadLetterCommand(),
+		cmd.CacheCommand(),
 	}
 
 	err := app.Run(os.Args)